@@ -0,0 +1,440 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"k.go/karabasan"
+)
+
+// openAPI describes the REST API.
+//
+//go:embed openapi.json
+var openAPI []byte
+
+// apiServer is the REST front end: every chat turn is one request, and the
+// sessions wait in memory between turns until they expire.
+type apiServer struct {
+	content *karabasan.Content
+	http    http.Server
+
+	// MaxSessions is how many sessions can exist at once; 0 means no limit.
+	MaxSessions int
+	// TTL is how long a session is kept after its last turn.
+	TTL time.Duration
+	// StoreDir, when set, keeps every session on disk too, so that they
+	// survive a restart.
+	StoreDir string
+	// ScoresPath is the score table the sessions save to.
+	ScoresPath string
+	// Clock tells the sessions' ages.
+	Clock karabasan.Clock
+
+	mu       sync.Mutex
+	sessions map[string]*apiSession
+}
+
+// apiSession is one session of the REST API. Its record is everything
+// needed to play it again: the seed, the options and the answers so far.
+type apiSession struct {
+	mu       sync.Mutex
+	s        *karabasan.Session
+	record   apiRecord
+	lastUsed time.Time
+}
+
+// apiRecord is how a session is stored on disk.
+type apiRecord struct {
+	ID         string   `json:"id"`
+	Seed       uint64   `json:"seed"`
+	LoginName  string   `json:"loginName,omitempty"`
+	Difficulty string   `json:"difficulty,omitempty"`
+	Inputs     []string `json:"inputs"`
+}
+
+// apiReply is the body of a successful turn.
+type apiReply struct {
+	ID       string              `json:"id"`
+	Messages []string            `json:"messages"`
+	Prompt   string              `json:"prompt,omitempty"`
+	Expect   karabasan.InputKind `json:"expect,omitempty"`
+	Stage    karabasan.StageID   `json:"stage"`
+	Done     bool                `json:"done"`
+}
+
+func newAPIServer(content *karabasan.Content) *apiServer {
+	srv := &apiServer{
+		content:  content,
+		TTL:      30 * time.Minute,
+		Clock:    karabasan.RealClock{},
+		sessions: map[string]*apiSession{},
+	}
+	srv.http.Handler = srv.handler()
+	return srv
+}
+
+// Serve answers HTTP requests on ln until Shutdown is called.
+func (srv *apiServer) Serve(ln net.Listener) error {
+	if err := srv.http.Serve(ln); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops taking requests and waits for the running turns. The
+// sessions themselves stay on disk, if they are stored.
+func (srv *apiServer) Shutdown(ctx context.Context) error {
+	return srv.http.Shutdown(ctx)
+}
+
+func (srv *apiServer) activeSessions() int {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return len(srv.sessions)
+}
+
+// handler routes the API.
+func (srv *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /sessions", srv.create)
+	mux.HandleFunc("POST /sessions/{id}/messages", srv.message)
+	mux.HandleFunc("DELETE /sessions/{id}", srv.delete)
+	mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPI)
+	})
+	return mux
+}
+
+// create starts a session and answers with its opening lines.
+func (srv *apiServer) create(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		Seed       uint64 `json:"seed"`
+		LoginName  string `json:"loginName"`
+		Difficulty string `json:"difficulty"`
+	}
+	if req.ContentLength != 0 {
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			apiError(w, http.StatusBadRequest, "the body is not a JSON object: "+err.Error())
+			return
+		}
+	}
+	if _, ok := srv.content.FindDifficulty(body.Difficulty); body.Difficulty != "" && !ok {
+		apiError(w, http.StatusBadRequest, fmt.Sprintf("unknown difficulty %q; choose one of %s", body.Difficulty, strings.Join(srv.content.DifficultyNames(), ", ")))
+		return
+	}
+
+	as := &apiSession{record: apiRecord{
+		ID:         newSessionID(),
+		Seed:       body.Seed,
+		LoginName:  body.LoginName,
+		Difficulty: body.Difficulty,
+	}}
+	as.s = srv.newSession(as.record)
+	as.record.Seed = as.s.Seed
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	// The slot is taken in the same critical section as the check, so that
+	// concurrent requests cannot go past the limit together.
+	srv.evict()
+	srv.mu.Lock()
+	full := srv.MaxSessions > 0 && len(srv.sessions) >= srv.MaxSessions
+	if !full {
+		srv.sessions[as.record.ID] = as
+	}
+	srv.mu.Unlock()
+	if full {
+		apiError(w, http.StatusServiceUnavailable, srv.content.Serve.Busy)
+		return
+	}
+	log.Printf("api %s: session started, seed %d", as.record.ID, as.record.Seed)
+
+	w.Header().Set("Location", "/sessions/"+as.record.ID)
+	srv.turn(w, http.StatusCreated, as, func() (karabasan.Reply, error) { return as.s.Start() })
+}
+
+// message gives a session the user's answer and answers with Karabasan's.
+func (srv *apiServer) message(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		Text *string `json:"text"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Text == nil {
+		apiError(w, http.StatusBadRequest, `the body must be a JSON object like {"text": "..."}`)
+		return
+	}
+	as := srv.lookup(req.PathValue("id"))
+	if as == nil {
+		apiError(w, http.StatusNotFound, "no such session; it may have expired")
+		return
+	}
+	defer as.mu.Unlock()
+	srv.turn(w, http.StatusOK, as, func() (karabasan.Reply, error) { return as.s.Respond(*body.Text) })
+}
+
+// delete ends a session.
+func (srv *apiServer) delete(w http.ResponseWriter, req *http.Request) {
+	as := srv.lookup(req.PathValue("id"))
+	if as == nil {
+		apiError(w, http.StatusNotFound, "no such session; it may have expired")
+		return
+	}
+	defer as.mu.Unlock()
+	srv.remove(as)
+	log.Printf("api %s: session deleted in %s", as.record.ID, as.s.Stage)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// turn plays one turn of a locked session and writes the reply. A session
+// that is done, or that crashed, is removed.
+func (srv *apiServer) turn(w http.ResponseWriter, status int, as *apiSession, play func() (karabasan.Reply, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			srv.remove(as)
+			path, err := writeCrashReport(as.s, r, debug.Stack())
+			if err != nil {
+				log.Printf("api %s: crashed in %s: %v (writing the crash report failed: %v)", as.record.ID, as.s.Stage, r, err)
+				path = "-"
+			} else {
+				log.Printf("api %s: crashed in %s: %v, see %s", as.record.ID, as.s.Stage, r, path)
+			}
+			apiError(w, http.StatusInternalServerError, fmt.Sprintf(srv.content.Crash, filepath.Base(path)))
+		}
+	}()
+	reply, err := play()
+	if err != nil {
+		srv.remove(as)
+		log.Printf("api %s: %v", as.record.ID, err)
+		apiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	as.record.Inputs = as.s.Inputs()
+	as.lastUsed = srv.Clock.Now()
+	if reply.Done {
+		srv.remove(as)
+		log.Printf("api %s: session ended", as.record.ID)
+	} else if err := srv.store(as); err != nil {
+		log.Printf("api %s: storing the session failed: %v", as.record.ID, err)
+	}
+
+	messages := reply.Messages
+	if messages == nil {
+		messages = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiReply{
+		ID:       as.record.ID,
+		Messages: messages,
+		Prompt:   reply.Prompt,
+		Expect:   reply.Expect,
+		Stage:    reply.Stage,
+		Done:     reply.Done,
+	})
+}
+
+// newSession creates the session a record describes, before its answers.
+func (srv *apiServer) newSession(rec apiRecord) *karabasan.Session {
+	return karabasan.NewSession(srv.content, karabasan.Options{
+		Seed:       rec.Seed,
+		LoginName:  rec.LoginName,
+		Difficulty: rec.Difficulty,
+		ScoresPath: srv.ScoresPath,
+		OnError:    func(err error) { log.Printf("api %s: %v", rec.ID, err) },
+	})
+}
+
+// lookup finds a live session and locks it.
+func (srv *apiServer) lookup(id string) *apiSession {
+	srv.evict()
+	srv.mu.Lock()
+	as := srv.sessions[id]
+	srv.mu.Unlock()
+	if as == nil {
+		return nil
+	}
+	as.mu.Lock()
+	srv.mu.Lock()
+	live := srv.sessions[id] == as
+	srv.mu.Unlock()
+	if !live {
+		// Removed while we waited for it.
+		as.mu.Unlock()
+		return nil
+	}
+	return as
+}
+
+// remove forgets a session, on disk too.
+func (srv *apiServer) remove(as *apiSession) {
+	srv.mu.Lock()
+	delete(srv.sessions, as.record.ID)
+	srv.mu.Unlock()
+	as.s.Close()
+	if srv.StoreDir != "" {
+		os.Remove(srv.storePath(as.record.ID))
+	}
+}
+
+// evict removes the sessions unused for longer than the TTL. Sessions in
+// the middle of a turn are skipped.
+func (srv *apiServer) evict() {
+	if srv.TTL <= 0 {
+		return
+	}
+	now := srv.Clock.Now()
+	srv.mu.Lock()
+	var expired []*apiSession
+	for _, as := range srv.sessions {
+		if as.mu.TryLock() {
+			if now.Sub(as.lastUsed) > srv.TTL {
+				expired = append(expired, as)
+			} else {
+				as.mu.Unlock()
+			}
+		}
+	}
+	srv.mu.Unlock()
+	for _, as := range expired {
+		srv.remove(as)
+		as.mu.Unlock()
+		log.Printf("api %s: session expired in %s", as.record.ID, as.s.Stage)
+	}
+}
+
+func (srv *apiServer) storePath(id string) string {
+	return filepath.Join(srv.StoreDir, id+".json")
+}
+
+// store writes a session's record to the store directory, if there is one.
+func (srv *apiServer) store(as *apiSession) error {
+	if srv.StoreDir == "" {
+		return nil
+	}
+	data, err := json.Marshal(as.record)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(srv.StoreDir, 0o755); err != nil {
+		return err
+	}
+	tmp := srv.storePath(as.record.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmp, as.lastUsed, as.lastUsed); err != nil {
+		return err
+	}
+	return os.Rename(tmp, srv.storePath(as.record.ID))
+}
+
+// load plays the stored sessions again up to where they were left. A
+// session's age is the age of its file.
+func (srv *apiServer) load() error {
+	if srv.StoreDir == "" {
+		return nil
+	}
+	matches, err := filepath.Glob(filepath.Join(srv.StoreDir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range matches {
+		if err := srv.restore(path); err != nil {
+			log.Printf("api: skipping %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+func (srv *apiServer) restore(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var rec apiRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return err
+	}
+	if rec.ID == "" || filepath.Base(path) != rec.ID+".json" {
+		return errors.New("the file name does not match the session id")
+	}
+
+	// Play the answers again without saving the rounds they finish; they
+	// were saved the first time.
+	s := srv.newSession(rec)
+	s.ScoresPath = ""
+	reply, err := s.Start()
+	for _, input := range rec.Inputs {
+		if err != nil || reply.Done {
+			break
+		}
+		reply, err = s.Respond(input)
+	}
+	if err != nil || reply.Done {
+		s.Close()
+		return fmt.Errorf("the answers do not replay: %v", err)
+	}
+	s.ScoresPath = srv.ScoresPath
+
+	srv.mu.Lock()
+	srv.sessions[rec.ID] = &apiSession{s: s, record: rec, lastUsed: info.ModTime()}
+	srv.mu.Unlock()
+	return nil
+}
+
+// apiError writes a JSON error body.
+func apiError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// newSessionID returns a random session id that cannot be guessed.
+func newSessionID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// runAPI is the "api" subcommand: the REST API.
+func runAPI(content *karabasan.Content, args []string) {
+	cmd := flag.NewFlagSet("api", flag.ExitOnError)
+	addr := cmd.String("addr", ":8081", "address to listen on")
+	maxSessions := cmd.Int("max-sessions", 1000, "number of sessions that can exist at once; 0 for no limit")
+	ttl := cmd.Duration("ttl", 30*time.Minute, "forget a session this long after its last message")
+	store := cmd.String("store", "", "keep the sessions in this directory so that they survive restarts")
+	grace := cmd.Duration("grace", 30*time.Second, "how long running requests may go on after SIGTERM")
+	cmd.Parse(args)
+
+	srv := newAPIServer(content)
+	srv.MaxSessions = *maxSessions
+	srv.TTL = *ttl
+	srv.StoreDir = *store
+	if path, err := karabasan.DefaultScoresPath(); err == nil {
+		srv.ScoresPath = path
+	}
+	if err := srv.load(); err != nil {
+		fmt.Println("Error loading sessions:", err)
+		os.Exit(1)
+	}
+	runSessionServer(srv, *addr, *grace)
+}
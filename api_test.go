@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k.go/karabasan"
+	"k.go/karabasan/clocktest"
+)
+
+func startAPIServer(t *testing.T, configure func(*apiServer)) (*apiServer, *httptest.Server) {
+	t.Helper()
+	content, err := karabasan.LoadContent("data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newAPIServer(content)
+	srv.Clock = clocktest.New()
+	if configure != nil {
+		configure(srv)
+	}
+	if err := srv.load(); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.handler())
+	t.Cleanup(ts.Close)
+	return srv, ts
+}
+
+// call sends a request with a JSON body and decodes the JSON answer into
+// reply, returning the status.
+func call(t *testing.T, method, url, body string, reply any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if reply != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(reply); err != nil {
+			t.Fatalf("%s %s: decoding the answer: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestAPIConversation(t *testing.T) {
+	_, ts := startAPIServer(t, nil)
+
+	var reply apiReply
+	if status := call(t, "POST", ts.URL+"/sessions", `{"seed": 1}`, &reply); status != http.StatusCreated {
+		t.Fatalf("create = %d, want %d", status, http.StatusCreated)
+	}
+	want := apiReply{
+		ID:       reply.ID,
+		Messages: []string{"Merhaba, hoş geldin.", "Ben yeni nesil bir terminal arayüzüyüm."},
+		Prompt:   "senin adın ne güzelim?",
+		Expect:   karabasan.InputText,
+		Stage:    "stage1",
+	}
+	if reply.ID == "" || !reflect.DeepEqual(reply, want) {
+		t.Errorf("create = %+v, want %+v", reply, want)
+	}
+
+	session := ts.URL + "/sessions/" + reply.ID
+	if status := call(t, "POST", session+"/messages", `{"text": "Ali"}`, &reply); status != http.StatusOK {
+		t.Fatalf("message = %d, want %d", status, http.StatusOK)
+	}
+	if len(reply.Messages) != 1 || !strings.Contains(reply.Messages[0], "Ali") || reply.Expect != karabasan.InputNumber || reply.Stage != "stage2" {
+		t.Errorf("message = %+v, want the greeting and the age question", reply)
+	}
+
+	if status := call(t, "DELETE", session, "", nil); status != http.StatusNoContent {
+		t.Errorf("delete = %d, want %d", status, http.StatusNoContent)
+	}
+	var failure map[string]string
+	if status := call(t, "POST", session+"/messages", `{"text": "30"}`, &failure); status != http.StatusNotFound || failure["error"] == "" {
+		t.Errorf("message after delete = %d %v, want %d with an error", status, failure, http.StatusNotFound)
+	}
+}
+
+func TestAPIRejectsBadRequests(t *testing.T) {
+	_, ts := startAPIServer(t, nil)
+	var failure map[string]string
+	if status := call(t, "POST", ts.URL+"/sessions", `{"difficulty": "imkansız"}`, &failure); status != http.StatusBadRequest {
+		t.Errorf("unknown difficulty = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	var reply apiReply
+	call(t, "POST", ts.URL+"/sessions", "", &reply)
+	for _, body := range []string{"", "Ali", `{"txt": "Ali"}`} {
+		if status := call(t, "POST", ts.URL+"/sessions/"+reply.ID+"/messages", body, &failure); status != http.StatusBadRequest {
+			t.Errorf("message %q = %d, want %d", body, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestAPISessionsExpire(t *testing.T) {
+	srv, ts := startAPIServer(t, func(srv *apiServer) { srv.TTL = time.Minute })
+	var reply apiReply
+	call(t, "POST", ts.URL+"/sessions", "", &reply)
+	messages := ts.URL + "/sessions/" + reply.ID + "/messages"
+
+	srv.Clock.(*clocktest.Clock).Advance(50 * time.Second)
+	if status := call(t, "POST", messages, `{"text": "Ali"}`, &reply); status != http.StatusOK {
+		t.Fatalf("message before the TTL = %d, want %d", status, http.StatusOK)
+	}
+	srv.Clock.(*clocktest.Clock).Advance(61 * time.Second)
+	if status := call(t, "POST", messages, `{"text": "30"}`, &reply); status != http.StatusNotFound {
+		t.Errorf("message after the TTL = %d, want %d", status, http.StatusNotFound)
+	}
+	if n := srv.activeSessions(); n != 0 {
+		t.Errorf("%d sessions left, want 0", n)
+	}
+}
+
+func TestAPISessionCap(t *testing.T) {
+	srv, ts := startAPIServer(t, func(srv *apiServer) { srv.MaxSessions = 1 })
+	call(t, "POST", ts.URL+"/sessions", "", &apiReply{})
+	var failure map[string]string
+	if status := call(t, "POST", ts.URL+"/sessions", "", &failure); status != http.StatusServiceUnavailable || failure["error"] != srv.content.Serve.Busy {
+		t.Errorf("second session = %d %v, want %d with the busy message", status, failure, http.StatusServiceUnavailable)
+	}
+}
+
+func TestAPISessionCapUnderLoad(t *testing.T) {
+	srv, ts := startAPIServer(t, func(srv *apiServer) { srv.MaxSessions = 3 })
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Go(func() {
+			resp, err := http.Post(ts.URL+"/sessions", "application/json", strings.NewReader(""))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		})
+	}
+	wg.Wait()
+	if n := srv.activeSessions(); n != srv.MaxSessions {
+		t.Errorf("%d sessions started at once, want the cap of %d", n, srv.MaxSessions)
+	}
+}
+
+func TestAPISessionsSurviveRestarts(t *testing.T) {
+	dir := t.TempDir()
+	_, first := startAPIServer(t, func(srv *apiServer) { srv.StoreDir = dir })
+	var reply apiReply
+	call(t, "POST", first.URL+"/sessions", `{"seed": 3}`, &reply)
+	id := reply.ID
+	call(t, "POST", first.URL+"/sessions/"+id+"/messages", `{"text": "Ali"}`, &reply)
+
+	// The next turn, played on a server started from the store, is the turn
+	// the first server would have played.
+	_, second := startAPIServer(t, func(srv *apiServer) { srv.StoreDir = dir })
+	var restored apiReply
+	if status := call(t, "POST", second.URL+"/sessions/"+id+"/messages", `{"text": "30"}`, &restored); status != http.StatusOK {
+		t.Fatalf("message after the restart = %d, want %d", status, http.StatusOK)
+	}
+	call(t, "POST", first.URL+"/sessions/"+id+"/messages", `{"text": "30"}`, &reply)
+	if !reflect.DeepEqual(restored, reply) {
+		t.Errorf("after the restart the session said %+v, want %+v", restored, reply)
+	}
+
+	if status := call(t, "DELETE", second.URL+"/sessions/"+id, "", nil); status != http.StatusNoContent {
+		t.Errorf("delete = %d, want %d", status, http.StatusNoContent)
+	}
+	_, third := startAPIServer(t, func(srv *apiServer) { srv.StoreDir = dir })
+	if status := call(t, "DELETE", third.URL+"/sessions/"+id, "", nil); status != http.StatusNotFound {
+		t.Errorf("a deleted session came back after a restart: %d", status)
+	}
+}
+
+func TestAPIServesItsDescription(t *testing.T) {
+	_, ts := startAPIServer(t, nil)
+	var doc struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if status := call(t, "GET", ts.URL+"/openapi.json", "", &doc); status != http.StatusOK {
+		t.Fatalf("GET /openapi.json = %d", status)
+	}
+	for path, method := range map[string]string{
+		"/sessions":               "post",
+		"/sessions/{id}/messages": "post",
+		"/sessions/{id}":          "delete",
+	} {
+		if doc.Paths[path][method] == nil {
+			t.Errorf("the description lacks %s %s", strings.ToUpper(method), path)
+		}
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, want a 3.x document", doc.OpenAPI)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"k.go/karabasan"
+)
+
+// stateDir returns $XDG_STATE_HOME/karabasan, falling back to ~/.local/state.
+func stateDir() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "karabasan"), nil
+}
+
+// crashReport describes a panic in a session. Everything but the user's
+// inputs is written as "#" comments, so the report doubles as a --script
+// that replays the crash with the recorded seed.
+func crashReport(s *karabasan.Session, r any, stack []byte, when time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# karabasan crash report, %s\n", when.Format(time.RFC3339))
+	fmt.Fprintf(&b, "# replay: karabasan --seed %d", s.Seed)
+	if d := s.PresetDifficulty(); d != "" {
+		fmt.Fprintf(&b, " --difficulty %s", d)
+	}
+	fmt.Fprintf(&b, " --script <this file>\n")
+	fmt.Fprintf(&b, "# seed: %d\n", s.Seed)
+	fmt.Fprintf(&b, "# crashed in stage: %s\n", s.Stage)
+	fmt.Fprintf(&b, "# panic: %v\n", r)
+	b.WriteString("#\n")
+	for _, line := range strings.Split(strings.TrimRight(string(stack), "\n"), "\n") {
+		fmt.Fprintf(&b, "# %s\n", line)
+	}
+	b.WriteString("#\n# inputs:\n")
+	for _, input := range s.Inputs() {
+		b.WriteString(input + "\n")
+	}
+	return b.String()
+}
+
+// writeCrashReport saves the report for a panic under the state directory
+// and returns its path.
+func writeCrashReport(s *karabasan.Session, r any, stack []byte) (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	path := filepath.Join(dir, fmt.Sprintf("crash-%s.txt", now.Format("20060102-150405")))
+	if err := os.WriteFile(path, []byte(crashReport(s, r, stack, now)), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// recoverCrash is deferred by main. On a panic it lets cleanup restore the
+// terminal, apologises in Karabasan's voice, writes a crash report and
+// exits, instead of leaving the user with a Go stack trace.
+func recoverCrash(s *karabasan.Session, cleanup func()) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := debug.Stack()
+	cleanup()
+	path, err := writeCrashReport(s, r, stack)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "karabasan crashed in %s (%v); writing the crash report failed: %v\n", s.Stage, r, err)
+		fmt.Fprintf(os.Stderr, "rerun with --seed %d to reproduce\n", s.Seed)
+		os.Exit(2)
+	}
+	message := s.Content().Crash
+	if message == "" {
+		message = "karabasan crashed; the crash report is in %s"
+	}
+	fmt.Fprintf(os.Stderr, "\n"+message+"\n", path)
+	os.Exit(2)
+}
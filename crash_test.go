@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"k.go/karabasan"
+)
+
+// TestCrashReportReplays checks that a crash report is itself a script with
+// the session's seed and inputs.
+func TestCrashReportReplays(t *testing.T) {
+	content, err := karabasan.LoadContent("data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := karabasan.NewTerminalSession(content, strings.NewReader("Ali\n30\n"), new(strings.Builder))
+	s.Plain = true
+	s.SetSeed(42)
+	s.Run("stage1")
+
+	report := crashReport(s, "boom", []byte("goroutine 1 [running]:\nmain.main()"), time.Unix(0, 0))
+	if !strings.Contains(report, "\n# seed: 42\n") || strings.Contains(report, "\n# stage:") {
+		t.Errorf("report does not replay from seed 42 at the start:\n%s", report)
+	}
+	if got := karabasan.ScriptAnswers(report); got != "Ali\n30\n" {
+		t.Errorf("report answers = %q, want %q", got, "Ali\n30\n")
+	}
+	if !strings.Contains(report, "# crashed in stage: stage3") || !strings.Contains(report, "# panic: boom") || !strings.Contains(report, "# main.main()") {
+		t.Errorf("report lacks the panic and stack:\n%s", report)
+	}
+}
+
+// TestCrashReportReplaysAskedDifficulty checks that a difficulty the user
+// typed is replayed from the inputs, not passed as --difficulty, which would
+// skip the question and shift every later answer.
+func TestCrashReportReplaysAskedDifficulty(t *testing.T) {
+	content, err := karabasan.LoadContent("data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := os.ReadFile("karabasan/testdata/full_conversation.script")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := karabasan.ScriptAnswers(string(script)) + "kolay\n50\n25\n"
+	run := func(inputs, difficulty string) (*karabasan.Session, string) {
+		var out strings.Builder
+		s := karabasan.NewTerminalSession(content, strings.NewReader(inputs), &out)
+		s.Plain = true
+		s.SetSeed(3)
+		s.Difficulty = difficulty
+		s.Run("stage1")
+		return s, out.String()
+	}
+	s, crashed := run(inputs, "")
+	if s.Difficulty != "kolay" {
+		t.Fatalf("Difficulty = %q, want kolay", s.Difficulty)
+	}
+
+	report := crashReport(s, "boom", nil, time.Unix(0, 0))
+	if strings.Contains(report, "--difficulty") {
+		t.Errorf("the report passes the asked difficulty as a flag:\n%s", report)
+	}
+	if _, replayed := run(karabasan.ScriptAnswers(report), s.PresetDifficulty()); replayed != crashed {
+		t.Errorf("the replay differs from the crashed session:\n%s\nwant:\n%s", replayed, crashed)
+	}
+
+	s, _ = run("Ali\n", "zor")
+	if report := crashReport(s, "boom", nil, time.Unix(0, 0)); !strings.Contains(report, " --difficulty zor ") {
+		t.Errorf("the report lacks the preset difficulty:\n%s", report)
+	}
+}
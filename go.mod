@@ -4,4 +4,11 @@ go 1.25.0
 
 require golang.org/x/term v0.34.0
 
-require golang.org/x/sys v0.35.0 // indirect
+require golang.org/x/sys v0.35.0
+
+require (
+	golang.org/x/crypto v0.41.0
+	golang.org/x/net v0.42.0
+)
+
+require golang.org/x/text v0.28.0
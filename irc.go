@@ -0,0 +1,521 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"k.go/karabasan"
+)
+
+// ircMaxText is how many bytes of text go into one PRIVMSG, leaving room
+// for the command, the target and the prefix the server adds within the
+// 512 byte line limit.
+const ircMaxText = 400
+
+// ircBot keeps Karabasan in an IRC channel. Everyone who addresses it gets
+// their own session, kept across reconnects until they leave, change nick
+// or go quiet for TTL.
+type ircBot struct {
+	content *karabasan.Content
+
+	// Server is the host:port to connect to, Channel the channel to join
+	// and Nick the nick to use.
+	Server  string
+	Channel string
+	Nick    string
+	// TLS connects with TLS.
+	TLS bool
+	// Notice answers with NOTICE instead of PRIVMSG, so that other bots do
+	// not answer back.
+	Notice bool
+	// FloodBurst lines are sent at once, then one every FloodInterval.
+	FloodBurst    int
+	FloodInterval time.Duration
+	// The wait before reconnecting doubles from MinBackoff to MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// PingTimeout is how long the server may stay silent before the
+	// connection is taken for dead.
+	PingTimeout time.Duration
+	// ScoresPath is the score table the sessions save to.
+	ScoresPath string
+	// TTL is how long a session is kept after its last turn.
+	TTL time.Duration
+	// Clock times the flood control, the backoff and the TTL.
+	Clock karabasan.Clock
+
+	nick  string              // the nick in use, which may differ from Nick
+	chats map[string]*ircChat // by lower-cased nick
+}
+
+// ircChat is one nick's session.
+type ircChat struct {
+	s        *karabasan.Session
+	lastUsed time.Time
+}
+
+func newIRCBot(content *karabasan.Content, server, channel, nick string) *ircBot {
+	return &ircBot{
+		content:       content,
+		Server:        server,
+		Channel:       channel,
+		Nick:          nick,
+		FloodBurst:    5,
+		FloodInterval: 2 * time.Second,
+		MinBackoff:    time.Second,
+		MaxBackoff:    5 * time.Minute,
+		PingTimeout:   5 * time.Minute,
+		TTL:           30 * time.Minute,
+		Clock:         karabasan.RealClock{},
+		chats:         map[string]*ircChat{},
+	}
+}
+
+// Run stays connected until ctx is done, reconnecting with backoff when the
+// connection is lost.
+func (b *ircBot) Run(ctx context.Context) {
+	backoff := b.MinBackoff
+	for {
+		registered, err := b.connect(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if registered {
+			backoff = b.MinBackoff
+		}
+		log.Printf("irc: %v; reconnecting in %s", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.Clock.After(backoff):
+		}
+		backoff = min(backoff*2, b.MaxBackoff)
+	}
+}
+
+// connect plays one connection to the server until it breaks or ctx is
+// done. registered tells whether the server ever welcomed the bot.
+func (b *ircBot) connect(ctx context.Context) (registered bool, err error) {
+	var conn net.Conn
+	if b.TLS {
+		conn, err = (&tls.Dialer{}).DialContext(ctx, "tcp", b.Server)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", b.Server)
+	}
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	log.Printf("irc: connected to %s", b.Server)
+
+	out := newIRCWriter(conn, b.Clock, b.FloodBurst, b.FloodInterval)
+	defer out.Close()
+	stop := context.AfterFunc(ctx, func() {
+		// Leave politely, past the flood queue.
+		out.writeNow(ircLine("QUIT", b.content.Serve.Shutdown))
+		conn.Close()
+	})
+	defer stop()
+
+	b.nick = b.Nick
+	out.Send(ircLine("NICK", b.nick))
+	out.Send(ircLine("USER", b.Nick, "0", "*", "Karabasan"))
+
+	lines := bufio.NewScanner(conn)
+	for {
+		if b.PingTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(b.PingTimeout))
+		}
+		if !lines.Scan() {
+			err := lines.Err()
+			if err == nil {
+				err = errors.New("the server closed the connection")
+			}
+			return registered, err
+		}
+		m := parseIRC(lines.Text())
+		if m.command == "001" {
+			registered = true
+		}
+		if m.command == "ERROR" {
+			return registered, fmt.Errorf("the server said: %s", m.param(0))
+		}
+		b.handle(m, out.Send)
+	}
+}
+
+// handle answers one message from the server.
+func (b *ircBot) handle(m ircMessage, send func(line string)) {
+	b.evict()
+	switch m.command {
+	case "PING":
+		send(ircLine("PONG", m.params...))
+	case "001":
+		send(ircLine("JOIN", b.Channel))
+	case "433": // nick in use
+		b.nick += "_"
+		send(ircLine("NICK", b.nick))
+	case "KICK":
+		if ircEqual(m.param(1), b.nick) {
+			send(ircLine("JOIN", b.Channel))
+		}
+	case "NICK":
+		if ircEqual(m.nick(), b.nick) {
+			b.nick = m.param(0)
+		} else {
+			b.endChat(m.nick())
+		}
+	case "PART", "QUIT":
+		b.endChat(m.nick())
+	case "PRIVMSG":
+		b.privmsg(m, send)
+	case "NOTICE":
+		// Never answered, so that two bots cannot keep each other talking.
+	}
+}
+
+// privmsg answers a message addressed to the bot, in the channel or in
+// private.
+func (b *ircBot) privmsg(m ircMessage, send func(line string)) {
+	from, target, text := m.nick(), m.param(0), m.param(1)
+	if from == "" || strings.HasPrefix(text, "\x01") {
+		// Server messages and CTCP requests are not conversation.
+		return
+	}
+	replyTo, prefix := from, ""
+	if !ircEqual(target, b.nick) {
+		var ok bool
+		if text, ok = addressedTo(text, b.nick); !ok {
+			return
+		}
+		replyTo, prefix = target, from+": "
+	}
+	command := "PRIVMSG"
+	if b.Notice {
+		command = "NOTICE"
+	}
+	for _, line := range b.chat(from, text) {
+		for _, part := range splitIRCText(prefix+line, ircMaxText) {
+			send(ircLine(command, replyTo, part))
+		}
+	}
+}
+
+// chat plays one turn of nick's session, starting one when they have none,
+// and returns Karabasan's lines.
+func (b *ircBot) chat(nick, text string) (lines []string) {
+	key := ircLower(nick)
+	c, ok := b.chats[key]
+	if !ok {
+		c = &ircChat{s: karabasan.NewSession(b.content, karabasan.Options{
+			LoginName:  nick,
+			ScoresPath: b.ScoresPath,
+			OnError:    func(err error) { log.Printf("irc %s: %v", nick, err) },
+		})}
+		b.chats[key] = c
+		log.Printf("irc %s: session started, seed %d", nick, c.s.Seed)
+	}
+	c.lastUsed = b.Clock.Now()
+	s := c.s
+	defer func() {
+		if r := recover(); r != nil {
+			b.endChat(nick)
+			path, err := writeCrashReport(s, r, debug.Stack())
+			if err != nil {
+				log.Printf("irc %s: crashed in %s: %v (writing the crash report failed: %v)", nick, s.Stage, r, err)
+				return
+			}
+			log.Printf("irc %s: crashed in %s: %v, see %s", nick, s.Stage, r, path)
+			lines = []string{fmt.Sprintf(b.content.Crash, filepath.Base(path))}
+		}
+	}()
+
+	// The line that started the session answers its first question, so
+	// that nothing the user said goes unheard.
+	var replies []karabasan.Reply
+	var reply karabasan.Reply
+	var err error
+	if !ok {
+		reply, err = s.Start()
+		replies = append(replies, reply)
+	}
+	if err == nil && !reply.Done && (ok || strings.TrimSpace(text) != "") {
+		reply, err = s.Respond(text)
+		replies = append(replies, reply)
+	}
+	if err != nil || reply.Done {
+		if err != nil {
+			log.Printf("irc %s: %v", nick, err)
+		}
+		b.endChat(nick)
+	}
+	for _, reply := range replies {
+		for _, message := range append(reply.Messages, reply.Prompt) {
+			for _, line := range strings.Split(message, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					lines = append(lines, line)
+				}
+			}
+		}
+	}
+	return lines
+}
+
+// endChat forgets nick's session.
+func (b *ircBot) endChat(nick string) {
+	if c, ok := b.chats[ircLower(nick)]; ok {
+		c.s.Close()
+		delete(b.chats, ircLower(nick))
+		log.Printf("irc %s: session ended in %s", nick, c.s.Stage)
+	}
+}
+
+// evict forgets the sessions unused for longer than the TTL.
+func (b *ircBot) evict() {
+	if b.TTL <= 0 {
+		return
+	}
+	now := b.Clock.Now()
+	for key, c := range b.chats {
+		if now.Sub(c.lastUsed) > b.TTL {
+			c.s.Close()
+			delete(b.chats, key)
+			log.Printf("irc %s: session expired in %s", key, c.s.Stage)
+		}
+	}
+}
+
+// addressedTo returns the text of a channel message that starts with
+// "nick:" or "nick,".
+func addressedTo(text, nick string) (string, bool) {
+	if len(text) <= len(nick) || !ircEqual(text[:len(nick)], nick) {
+		return "", false
+	}
+	rest := text[len(nick):]
+	if rest[0] != ':' && rest[0] != ',' {
+		return "", false
+	}
+	return strings.TrimSpace(rest[1:]), true
+}
+
+// ircMessage is one line from the server: ":prefix COMMAND params :trailing".
+type ircMessage struct {
+	prefix  string
+	command string
+	params  []string
+}
+
+func parseIRC(line string) ircMessage {
+	var m ircMessage
+	line = strings.TrimRight(line, "\r\n")
+	if strings.HasPrefix(line, "@") {
+		// Message tags are not used.
+		_, line, _ = strings.Cut(line, " ")
+	}
+	if strings.HasPrefix(line, ":") {
+		m.prefix, line, _ = strings.Cut(line[1:], " ")
+	}
+	m.command, line, _ = strings.Cut(line, " ")
+	m.command = strings.ToUpper(m.command)
+	for line != "" {
+		if strings.HasPrefix(line, ":") {
+			m.params = append(m.params, line[1:])
+			break
+		}
+		var param string
+		param, line, _ = strings.Cut(line, " ")
+		if param != "" {
+			m.params = append(m.params, param)
+		}
+	}
+	return m
+}
+
+// param returns parameter i, or "" when there are fewer.
+func (m ircMessage) param(i int) string {
+	if i < len(m.params) {
+		return m.params[i]
+	}
+	return ""
+}
+
+// nick returns the nick in a "nick!user@host" prefix.
+func (m ircMessage) nick() string {
+	nick, _, _ := strings.Cut(m.prefix, "!")
+	if strings.Contains(nick, ".") {
+		return "" // a server, not a user
+	}
+	return nick
+}
+
+// ircLine formats a command; the last parameter may contain spaces.
+func ircLine(command string, params ...string) string {
+	var b strings.Builder
+	b.WriteString(command)
+	for i, param := range params {
+		b.WriteByte(' ')
+		if i == len(params)-1 && (param == "" || strings.ContainsRune(param, ' ') || strings.HasPrefix(param, ":")) {
+			b.WriteByte(':')
+		}
+		b.WriteString(strings.NewReplacer("\r", " ", "\n", " ").Replace(param))
+	}
+	return b.String()
+}
+
+// ircLower folds a nick the way IRC servers compare them: {}|^ are the
+// lower case of []\~.
+func ircLower(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '[':
+			return '{'
+		case ']':
+			return '}'
+		case '\\':
+			return '|'
+		case '~':
+			return '^'
+		}
+		if r >= 'A' && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, s)
+}
+
+func ircEqual(a, b string) bool {
+	return ircLower(a) == ircLower(b)
+}
+
+// splitIRCText breaks text into pieces of at most max bytes, at spaces
+// where it can and never inside a UTF-8 character.
+func splitIRCText(text string, max int) []string {
+	var parts []string
+	for len(text) > max {
+		cut := strings.LastIndexByte(text[:max+1], ' ')
+		if cut <= 0 {
+			cut = max
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+		}
+		parts = append(parts, text[:cut])
+		text = strings.TrimLeft(text[cut:], " ")
+	}
+	return append(parts, text)
+}
+
+// ircWriter sends lines to the server no faster than its flood control
+// allows, so that the server does not disconnect the bot for flooding.
+type ircWriter struct {
+	conn     net.Conn
+	clock    karabasan.Clock
+	burst    int
+	interval time.Duration
+	queue    chan string
+	done     chan struct{}
+	next     time.Time // when the line after the burst may go
+}
+
+func newIRCWriter(conn net.Conn, clock karabasan.Clock, burst int, interval time.Duration) *ircWriter {
+	w := &ircWriter{
+		conn:     conn,
+		clock:    clock,
+		burst:    max(burst, 1),
+		interval: interval,
+		queue:    make(chan string, 256),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Send queues a line.
+func (w *ircWriter) Send(line string) {
+	select {
+	case w.queue <- line:
+	case <-w.done:
+	}
+}
+
+// Close drops the lines still queued.
+func (w *ircWriter) Close() {
+	select {
+	case <-w.done:
+	default:
+		close(w.done)
+	}
+}
+
+func (w *ircWriter) run() {
+	for {
+		select {
+		case <-w.done:
+			return
+		case line := <-w.queue:
+			w.wait()
+			if err := w.writeNow(line); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// wait holds a line back while the burst is used up.
+func (w *ircWriter) wait() {
+	now := w.clock.Now()
+	if w.next.Before(now) {
+		w.next = now
+	}
+	if ahead := w.next.Sub(now) - time.Duration(w.burst-1)*w.interval; ahead > 0 {
+		w.clock.Sleep(ahead)
+	}
+	w.next = w.next.Add(w.interval)
+}
+
+func (w *ircWriter) writeNow(line string) error {
+	w.conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	_, err := w.conn.Write([]byte(line + "\r\n"))
+	return err
+}
+
+// runIRC is the "irc" subcommand: Karabasan as an IRC bot.
+func runIRC(content *karabasan.Content, args []string) {
+	cmd := flag.NewFlagSet("irc", flag.ExitOnError)
+	server := cmd.String("server", "localhost:6667", "IRC server to connect to, as host:port")
+	channel := cmd.String("channel", "#karabasan", "channel to join")
+	nick := cmd.String("nick", "karabasan", "nick to use; people address the bot as \"nick: ...\"")
+	useTLS := cmd.Bool("tls", false, "connect with TLS")
+	notice := cmd.Bool("notice", false, "answer with NOTICE instead of PRIVMSG")
+	ttl := cmd.Duration("ttl", 30*time.Minute, "forget a session this long after its last message")
+	cmd.Parse(args)
+	if !strings.HasPrefix(*channel, "#") && !strings.HasPrefix(*channel, "&") {
+		fmt.Printf("Channel %q must start with # or &.\n", *channel)
+		os.Exit(1)
+	}
+
+	b := newIRCBot(content, *server, *channel, *nick)
+	b.TLS = *useTLS
+	b.Notice = *notice
+	b.TTL = *ttl
+	if path, err := karabasan.DefaultScoresPath(); err == nil {
+		b.ScoresPath = path
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	b.Run(ctx)
+}
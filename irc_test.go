@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"k.go/karabasan"
+	"k.go/karabasan/clocktest"
+)
+
+// fakeIRCServer is an in-process IRC server the bot connects to; the test
+// plays the server's side line by line.
+type fakeIRCServer struct {
+	t     *testing.T
+	ln    net.Listener
+	conns chan net.Conn
+}
+
+func newFakeIRCServer(t *testing.T) *fakeIRCServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &fakeIRCServer{t: t, ln: ln, conns: make(chan net.Conn, 4)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			srv.conns <- conn
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return srv
+}
+
+// accept waits for the bot to connect.
+func (srv *fakeIRCServer) accept() *fakeIRCClient {
+	srv.t.Helper()
+	select {
+	case conn := <-srv.conns:
+		srv.t.Cleanup(func() { conn.Close() })
+		c := &fakeIRCClient{t: srv.t, conn: conn, lines: make(chan string, 256)}
+		go func() {
+			defer close(c.lines)
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				c.lines <- scanner.Text()
+			}
+		}()
+		return c
+	case <-time.After(5 * time.Second):
+		srv.t.Fatal("the bot did not connect")
+		return nil
+	}
+}
+
+// fakeIRCClient is the server's end of one connection of the bot.
+type fakeIRCClient struct {
+	t     *testing.T
+	conn  net.Conn
+	lines chan string
+}
+
+func (c *fakeIRCClient) send(line string) {
+	c.t.Helper()
+	if _, err := c.conn.Write([]byte(line + "\r\n")); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// next returns the next line the bot sent.
+func (c *fakeIRCClient) next() string {
+	c.t.Helper()
+	select {
+	case line, ok := <-c.lines:
+		if !ok {
+			c.t.Fatal("the bot hung up")
+		}
+		return line
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("the bot sent nothing")
+		return ""
+	}
+}
+
+// expect skips lines until one contains want.
+func (c *fakeIRCClient) expect(want string) string {
+	c.t.Helper()
+	var seen []string
+	for {
+		select {
+		case line, ok := <-c.lines:
+			if !ok {
+				c.t.Fatalf("the bot hung up waiting for %q; sent:\n%s", want, strings.Join(seen, "\n"))
+			}
+			if strings.Contains(line, want) {
+				return line
+			}
+			seen = append(seen, line)
+		case <-time.After(5 * time.Second):
+			c.t.Fatalf("the bot did not send %q; sent:\n%s", want, strings.Join(seen, "\n"))
+		}
+	}
+}
+
+// quiet checks that the bot sends nothing more before the answer to a PING.
+func (c *fakeIRCClient) quiet() {
+	c.t.Helper()
+	c.send("PING :check")
+	if line := c.next(); line != "PONG check" {
+		c.t.Errorf("the bot sent %q, want nothing", line)
+	}
+}
+
+// startIRCBot runs a bot against a fake server and returns the server's
+// end of the registered connection.
+func startIRCBot(t *testing.T, configure func(*ircBot)) (*ircBot, *fakeIRCServer, *fakeIRCClient) {
+	t.Helper()
+	content, err := karabasan.LoadContent("data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newFakeIRCServer(t)
+	b := newIRCBot(content, srv.ln.Addr().String(), "#test", "karabasan")
+	b.Clock = clocktest.New()
+	if configure != nil {
+		configure(b)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	c := srv.accept()
+	c.expect("NICK karabasan")
+	c.expect("USER karabasan 0 * Karabasan")
+	c.send(":irc.test 001 karabasan :Welcome")
+	c.expect("JOIN #test")
+	return b, srv, c
+}
+
+func TestIRCAnswersPing(t *testing.T) {
+	_, _, c := startIRCBot(t, nil)
+	c.send("PING :irc.test")
+	if line := c.next(); line != "PONG irc.test" {
+		t.Errorf("got %q, want the PONG", line)
+	}
+}
+
+func TestIRCTakesAnotherNickWhenTaken(t *testing.T) {
+	b, _, c := startIRCBot(t, nil)
+	c.send(":irc.test 433 karabasan karabasan :Nickname is already in use")
+	c.expect("NICK karabasan_")
+	c.send(":bob!b@h PRIVMSG #test :karabasan_: selam")
+	c.expect("PRIVMSG #test :bob: Merhaba, hoş geldin.")
+	if b.nick != "karabasan_" {
+		t.Errorf("nick = %q", b.nick)
+	}
+}
+
+func TestIRCKeepsASessionPerNick(t *testing.T) {
+	_, _, c := startIRCBot(t, nil)
+	c.send(":ali!a@h PRIVMSG #test :karabasan:")
+	c.expect("PRIVMSG #test :ali: Merhaba, hoş geldin.")
+	c.expect("PRIVMSG #test :ali: ali diye girdin... adın bu mu yoksa uydurdun mu? (e/h)")
+
+	// The line that starts a session answers its first question.
+	c.send(":veli!v@h PRIVMSG #test :KARABASAN, h")
+	c.expect("PRIVMSG #test :veli: veli diye girdin")
+	c.expect("PRIVMSG #test :veli: senin adın ne güzelim?")
+
+	c.send(":ali!a@h PRIVMSG #test :karabasan: e")
+	c.expect("PRIVMSG #test :ali: Tanıştığıma memnun oldum, ali. Hadi başlayalım.")
+	c.expect("PRIVMSG #test :ali: kaç yaşındasın?")
+
+	// Only messages addressed to the bot are answered.
+	c.send(":ali!a@h PRIVMSG #test :karabasan ne diyor")
+	c.send(":ali!a@h PRIVMSG #test :30")
+	c.quiet()
+}
+
+func TestIRCForgetsSessions(t *testing.T) {
+	b, _, c := startIRCBot(t, func(b *ircBot) { b.TTL = time.Hour })
+	for _, nick := range []string{"ali", "veli", "ayşe"} {
+		c.send(":" + nick + "!u@h PRIVMSG #test :karabasan:")
+		c.expect("PRIVMSG #test :" + nick + ": " + nick + " diye girdin")
+	}
+	c.send(":ali!a@h NICK :alican")
+	c.send(":veli!v@h PART #test :bye")
+	c.quiet()
+	if _, ok := b.chats["ali"]; ok || len(b.chats) != 1 {
+		t.Errorf("sessions after a nick change and a part: %v, want only ayşe's", b.chats)
+	}
+
+	// A new nick starts over.
+	c.send(":alican!a@h PRIVMSG #test :karabasan:")
+	c.expect("PRIVMSG #test :alican: alican diye girdin")
+
+	// Sessions quiet for longer than the TTL are dropped with the next line
+	// from the server.
+	b.Clock.(*clocktest.Clock).Advance(2 * time.Hour)
+	c.quiet()
+	if len(b.chats) != 0 {
+		t.Errorf("%d sessions left after the TTL, want 0", len(b.chats))
+	}
+}
+
+func TestIRCPrivateMessagesAndNotices(t *testing.T) {
+	_, _, c := startIRCBot(t, func(b *ircBot) { b.Notice = true })
+	c.send(":ali!a@h PRIVMSG karabasan :e")
+	c.expect("NOTICE ali :Merhaba, hoş geldin.")
+	c.expect("NOTICE ali :ali diye girdin")
+	c.expect("NOTICE ali :Tanıştığıma memnun oldum, ali.")
+	c.expect("NOTICE ali :kaç yaşındasın?")
+
+	// Notices and CTCP requests are never answered.
+	c.send(":ali!a@h NOTICE karabasan :e")
+	c.send(":ali!a@h PRIVMSG karabasan :\x01VERSION\x01")
+	c.quiet()
+}
+
+func TestIRCReconnectsAndKeepsSessions(t *testing.T) {
+	_, srv, c := startIRCBot(t, func(b *ircBot) {
+		b.Clock = karabasan.RealClock{}
+		b.MinBackoff = 10 * time.Millisecond
+	})
+	c.send(":ali!a@h PRIVMSG #test :karabasan:")
+	c.expect("(e/h)")
+	c.conn.Close()
+
+	c = srv.accept()
+	c.expect("NICK karabasan")
+	c.send(":irc.test 001 karabasan :Welcome")
+	c.expect("JOIN #test")
+	c.send(":ali!a@h PRIVMSG #test :karabasan: e")
+	c.expect("PRIVMSG #test :ali: Tanıştığıma memnun oldum, ali.")
+}
+
+func TestIRCWriterHoldsBackFloods(t *testing.T) {
+	bot, server := net.Pipe()
+	defer bot.Close()
+	go func() {
+		scanner := bufio.NewScanner(server)
+		for scanner.Scan() {
+		}
+	}()
+	clock := clocktest.New()
+	w := newIRCWriter(bot, clock, 5, 2*time.Second)
+	defer w.Close()
+	for range 8 {
+		w.Send("PRIVMSG #test :hehe")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for clock.Slept() < 6*time.Second && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	// The first five go at once, each of the other three waits 2s.
+	if clock.Slept() != 6*time.Second {
+		t.Errorf("the writer waited %v, want 6s", clock.Slept())
+	}
+}
+
+func TestParseIRC(t *testing.T) {
+	for line, want := range map[string]ircMessage{
+		"PING :irc.test": {command: "PING", params: []string{"irc.test"}},
+		":ali!a@h PRIVMSG #test :karabasan: naber":    {prefix: "ali!a@h", command: "PRIVMSG", params: []string{"#test", "karabasan: naber"}},
+		"@time=2020 :irc.test 001 karabasan :Welcome": {prefix: "irc.test", command: "001", params: []string{"karabasan", "Welcome"}},
+		":ali!a@h nick alican\r\n":                    {prefix: "ali!a@h", command: "NICK", params: []string{"alican"}},
+		":irc.test 433 * karabasan :Nickname in use":  {prefix: "irc.test", command: "433", params: []string{"*", "karabasan", "Nickname in use"}},
+		":ali!a@h PRIVMSG karabasan ::) selam":        {prefix: "ali!a@h", command: "PRIVMSG", params: []string{"karabasan", ":) selam"}},
+	} {
+		if got := parseIRC(line); !reflect.DeepEqual(got, want) {
+			t.Errorf("parseIRC(%q) = %+v, want %+v", line, got, want)
+		}
+	}
+}
+
+func TestSplitIRCText(t *testing.T) {
+	text := strings.Repeat("şşş ğğğğ ", 60) + strings.Repeat("ü", 300)
+	parts := splitIRCText(text, 100)
+	for _, part := range parts {
+		if len(part) > 100 || !utf8.ValidString(part) {
+			t.Errorf("bad part %q (%d bytes)", part, len(part))
+		}
+	}
+	if got := strings.ReplaceAll(strings.Join(parts, ""), " ", ""); got != strings.ReplaceAll(text, " ", "") {
+		t.Errorf("the parts lost text")
+	}
+}
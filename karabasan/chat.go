@@ -0,0 +1,343 @@
+package karabasan
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"k.go/karabasan/turkish"
+)
+
+// Chat is the content of the free conversation held after stage10, in the
+// manner of ELIZA: the user's line is matched against ranked rules, and the
+// first decomposition that fits picks a reassembly template.
+//
+// Templates may use {1}…{9} for the decomposition's groups, reflected from
+// the user's point of view to Karabasan's. Earlier answers come in as in
+// the rest of the content, {{.Name}} or {{locative .Hometown}}; a template
+// whose answer is not known yet is skipped.
+// A template that is only {joke} or {laugh} tells a joke or laughs, and
+// {memory} recalls an earlier answer through one of Memories.
+type Chat struct {
+	Intro     string     `json:"intro"`
+	Prompt    string     `json:"prompt"`
+	Silence   string     `json:"silence"`
+	Quit      []string   `json:"quit"`
+	Goodbye   string     `json:"goodbye"`
+	Rules     []ChatRule `json:"rules"`
+	Memories  []string   `json:"memories"`
+	Fallbacks []string   `json:"fallbacks"`
+
+	// Reflections swap whole words, such as ben and sen; Suffixes swap the
+	// person endings of the words left, such as -yorum and -yorsun.
+	Reflections map[string]string `json:"reflections"`
+	Suffixes    map[string]string `json:"suffixes"`
+}
+
+// ChatRule fires when one of its keywords is a word of the user's line, or
+// when its pattern matches the line. A keyword ending in * matches any word
+// it starts, so that anne* also catches annem and anneni. Rules of a higher
+// rank are tried first.
+type ChatRule struct {
+	Keywords       []string            `json:"keywords,omitempty"`
+	Pattern        ChatPattern         `json:"pattern,omitzero"`
+	Rank           int                 `json:"rank,omitempty"`
+	Decompositions []ChatDecomposition `json:"decompositions"`
+}
+
+// ChatDecomposition takes the line apart with Pattern, an empty pattern
+// matching any line, and answers with one of Replies. Remember templates
+// are filled in too and kept for a later turn that no rule matches.
+type ChatDecomposition struct {
+	Pattern  ChatPattern `json:"pattern,omitzero"`
+	Replies  []string    `json:"replies"`
+	Remember []string    `json:"remember,omitempty"`
+}
+
+// ChatPattern is a regular expression written in data.json as a string. It
+// is matched against the lowercased line.
+type ChatPattern struct {
+	*regexp.Regexp
+}
+
+func (p *ChatPattern) UnmarshalJSON(data []byte) error {
+	var expr string
+	if err := json.Unmarshal(data, &expr); err != nil {
+		return err
+	}
+	if expr == "" {
+		p.Regexp = nil
+		return nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("chat pattern: %w", err)
+	}
+	p.Regexp = re
+	return nil
+}
+
+// match returns the groups of line, with the whole match first, or nil when
+// the pattern does not match. An empty pattern matches the whole line.
+func (p ChatPattern) match(line string) []string {
+	if p.Regexp == nil {
+		return []string{line}
+	}
+	return p.FindStringSubmatch(line)
+}
+
+// chatWords splits a lowercased line into its words.
+func chatWords(line string) []string {
+	return strings.FieldsFunc(line, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
+
+// normalizeChat lowercases line and drops the punctuation around it.
+func normalizeChat(line string) string {
+	line = strings.Join(strings.Fields(turkish.Lower(line)), " ")
+	return strings.TrimFunc(line, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r)
+	})
+}
+
+// hasKeyword reports whether keyword, one or more words with an optional
+// trailing *, appears among words. Diacritics are not minded, for those who
+// write "gorusuruz" on a keyboard without Turkish letters.
+func hasKeyword(words []string, keyword string) bool {
+	prefix := strings.HasSuffix(keyword, "*")
+	want := strings.Fields(strings.TrimSuffix(turkish.Fold(keyword), "*"))
+	if len(want) == 0 {
+		return false
+	}
+	folded := make([]string, len(words))
+	for i, word := range words {
+		folded[i] = turkish.Fold(word)
+	}
+	words = folded
+	for i := 0; i+len(want) <= len(words); i++ {
+		last := len(want) - 1
+		if !slices.Equal(words[i:i+last], want[:last]) {
+			continue
+		}
+		if word := words[i+last]; word == want[last] || prefix && strings.HasPrefix(word, want[last]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the rule fires for the line and its words.
+func (r ChatRule) matches(line string, words []string) bool {
+	for _, keyword := range r.Keywords {
+		if hasKeyword(words, keyword) {
+			return true
+		}
+	}
+	return r.Pattern.Regexp != nil && r.Pattern.MatchString(line)
+}
+
+// reflect turns a piece of the user's line around: ben becomes sen,
+// geliyorum becomes geliyorsun, and so on.
+func (c Chat) reflect(text string) string {
+	words := strings.Fields(text)
+	for i, word := range words {
+		if swap, ok := c.Reflections[word]; ok {
+			words[i] = swap
+			continue
+		}
+		words[i] = c.reflectSuffix(word)
+	}
+	return strings.Join(words, " ")
+}
+
+// reflectSuffix swaps the longest ending of word found in Suffixes. The
+// stem has to keep two letters, so that short words are left alone, and
+// a buffer y goes between a vowel and an ending that starts with one.
+func (c Chat) reflectSuffix(word string) string {
+	best := ""
+	for suffix := range c.Suffixes {
+		if len(suffix) > len(best) && strings.HasSuffix(word, suffix) && utf8.RuneCountInString(word)-utf8.RuneCountInString(suffix) >= 2 {
+			best = suffix
+		}
+	}
+	if best == "" {
+		return word
+	}
+	stem, ending := strings.TrimSuffix(word, best), c.Suffixes[best]
+	last, _ := utf8.DecodeLastRuneInString(stem)
+	first, _ := utf8.DecodeRuneInString(ending)
+	if turkish.IsVowel(last) && turkish.IsVowel(first) {
+		stem += "y"
+	}
+	return stem + ending
+}
+
+// chatValues returns the placeholders a template can use this turn.
+func (s *Session) chatValues(groups []string) map[string]string {
+	values := map[string]string{}
+	for i, group := range groups {
+		if i > 0 && i <= 9 && group != "" {
+			values[strconv.Itoa(i)] = s.content.Stages.Chat.reflect(group)
+		}
+	}
+	return values
+}
+
+var chatPlaceholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// fillChat fills in the placeholders of template; ok is false when one of
+// them has no value this turn. {joke}, {laugh} and {memory} are left alone
+// for sayChat.
+func fillChat(template string, values map[string]string) (text string, ok bool) {
+	ok = true
+	text = chatPlaceholder.ReplaceAllStringFunc(template, func(m string) string {
+		key := m[1 : len(m)-1]
+		if key == "joke" || key == "laugh" || key == "memory" {
+			return m
+		}
+		value, found := values[key]
+		if !found {
+			ok = false
+		}
+		return value
+	})
+	return text, ok
+}
+
+// answered reports whether the answers template refers to are known yet.
+func (s *Session) answered(template string) bool {
+	for field, known := range map[string]bool{
+		".Name":     s.UserName != "",
+		".Age":      s.Age > 0,
+		".Height":   s.Height > 0,
+		".Weight":   s.Weight > 0,
+		".Hometown": s.Hometown != "",
+	} {
+		if !known && strings.Contains(template, field) {
+			return false
+		}
+	}
+	return true
+}
+
+// usableChat fills in every template that has all its values, in order.
+// The content template runs before the groups go in, so that the user's
+// words are never run as a template.
+func (s *Session) usableChat(templates []string, values map[string]string) []string {
+	var usable []string
+	for _, template := range templates {
+		if !s.answered(template) {
+			continue
+		}
+		if text, ok := fillChat(s.render(template), values); ok {
+			usable = append(usable, text)
+		}
+	}
+	return usable
+}
+
+// sayChat says a filled-in template, telling a joke, laughing or recalling
+// an earlier answer for the special ones.
+func (s *Session) sayChat(text string) {
+	switch text {
+	case "{joke}":
+		s.sayJoke()
+	case "{laugh}":
+		s.laugh()
+	case "{memory}":
+		if memories := s.usableChat(s.content.Stages.Chat.Memories, nil); len(memories) > 0 {
+			s.aiResponse(s.randomLine(memories))
+		} else {
+			s.laugh()
+		}
+	default:
+		s.aiResponse(text)
+	}
+}
+
+// chatRules returns the rules by rank, keeping the data.json order within one.
+func (c Chat) chatRules() []ChatRule {
+	rules := slices.Clone(c.Rules)
+	slices.SortStableFunc(rules, func(a, b ChatRule) int { return b.Rank - a.Rank })
+	return rules
+}
+
+// chatReply answers one line of free chat.
+func (s *Session) chatReply(input string) {
+	chat := s.content.Stages.Chat
+	line := normalizeChat(input)
+	if line == "" {
+		s.aiResponse(s.render(chat.Silence))
+		return
+	}
+	words := chatWords(line)
+	for _, rule := range chat.chatRules() {
+		if !rule.matches(line, words) {
+			continue
+		}
+		for _, d := range rule.Decompositions {
+			groups := d.Pattern.match(line)
+			if groups == nil {
+				continue
+			}
+			values := s.chatValues(groups)
+			replies := s.usableChat(d.Replies, values)
+			if len(replies) == 0 {
+				continue
+			}
+			s.chatMemory = append(s.chatMemory, s.usableChat(d.Remember, values)...)
+			s.sayChat(s.randomLine(replies))
+			return
+		}
+	}
+
+	// Nothing matched: bring up something the user said before, let the
+	// Responder have a go, or fall back.
+	if len(s.chatMemory) > 0 && s.randomInt(2) == 0 {
+		s.aiResponse(s.chatMemory[0])
+		s.chatMemory = s.chatMemory[1:]
+		return
+	}
+	if reply, ok := s.freeReply("", input); ok {
+		s.aiResponse(reply)
+		return
+	}
+	s.sayChat(s.randomLine(s.usableChat(chat.Fallbacks, nil)))
+}
+
+// quits reports whether the line ends the chat.
+func (c Chat) quits(input string) bool {
+	words := chatWords(normalizeChat(input))
+	for _, word := range c.Quit {
+		if hasKeyword(words, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// stageChat keeps the conversation going after the games until the user
+// says goodbye.
+func (s *Session) stageChat() StageID {
+	chat := s.content.Stages.Chat
+	if len(chat.Rules) == 0 && len(chat.Fallbacks) == 0 {
+		return replayID
+	}
+	s.chatMemory = nil
+	s.aiResponse(s.render(chat.Intro, s.UserName))
+	for {
+		s.userPrompt(s.render(chat.Prompt))
+		input := s.readLine()
+		if chat.quits(input) {
+			s.aiResponse(s.render(chat.Goodbye))
+			return replayID
+		}
+		s.chatReply(input)
+	}
+}
@@ -0,0 +1,100 @@
+package karabasan
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChatReflect(t *testing.T) {
+	content, err := LoadContent("../data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chat := content.Stages.Chat
+	for in, want := range map[string]string{
+		"ben seni seviyorum":     "sen beni seviyorsun",
+		"sen çok akıllısın":      "ben çok akıllıyım",
+		"dün ankaraya gittim":    "dün ankaraya gittin",
+		"sana bişey diyeceğim":   "bana bişey diyeceğim",
+		"bizim ev çok uzakta":    "sizin ev çok uzakta",
+		"salaksın":               "salakım",
+		"sen ne diyorsun":        "ben ne diyorum",
+		"o okula geliyor":        "o okula geliyor",
+		"yüm":                    "yüm",
+		"ev":                     "ev",
+		"benden senden bizden ı": "senden benden bizden ı",
+	} {
+		if got := chat.reflect(in); got != want {
+			t.Errorf("reflect(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestChatKeywords(t *testing.T) {
+	words := chatWords(normalizeChat("Annemi ÇOK seviyorum, ne haber? GORUSURUZ"))
+	for keyword, want := range map[string]bool{
+		"anne*":      true,
+		"anne":       false,
+		"çok":        true,
+		"ne haber":   true,
+		"ne":         true,
+		"haber*":     true,
+		"baba*":      false,
+		"*":          false,
+		"görüşürüz":  true,
+		"görüşürüm*": false,
+	} {
+		if got := hasKeyword(words, keyword); got != want {
+			t.Errorf("hasKeyword(%q, %q) = %v, want %v", words, keyword, got, want)
+		}
+	}
+}
+
+func TestChatRemembersAnswers(t *testing.T) {
+	content, err := LoadContent("../data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	s := NewTerminalSession(content, strings.NewReader(""), &out)
+	s.Plain = true
+	s.UserName, s.Age, s.Hometown = "Ali", 30, "Isparta"
+	for range 20 {
+		s.chatReply("memleketim güzeldir")
+		s.chatReply("yaşım geldi")
+	}
+	for _, want := range []string{"Isparta", "30 yaşında"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("the chat never brought up %q:\n%s", want, out.String())
+		}
+	}
+
+	// Without the answers those replies are skipped, not said half empty.
+	s = NewTerminalSession(content, strings.NewReader(""), io.Discard)
+	s.Plain = true
+	s.UserName = "Ali"
+	if replies := s.usableChat(content.Stages.Chat.Memories, nil); len(replies) != 1 {
+		t.Errorf("usable memories without age and hometown = %q, want only the one by name", replies)
+	}
+}
+
+func TestChatTemplates(t *testing.T) {
+	content, err := LoadContent("../data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewTerminalSession(content, strings.NewReader(""), io.Discard)
+	s.UserName, s.Hometown = "ali", "sinop"
+	values := map[string]string{"1": "{{.Name}}"}
+	got := s.usableChat([]string{"{{ablative .Hometown}} gelen {{.Name}} {1} demiş"}, values)
+	if want := "Sinop'tan gelen Ali {{.Name}} demiş"; len(got) != 1 || got[0] != want {
+		t.Errorf("usableChat = %q, want %q", got, want)
+	}
+
+	content.Stages.Chat.Rules[0].Decompositions[0].Replies = []string{"{{locative .Hometown"}
+	if err := checkTemplates(reflect.ValueOf(content).Elem(), "content"); err == nil || !strings.Contains(err.Error(), "Chat.Rules[0]") {
+		t.Errorf("a broken chat reply was reported as %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package karabasan
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the time source of the conversation's timed effects: typing,
+// thinking and pauses. Tests swap in a fake one to run those effects
+// without waiting.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+}
+
+// RealClock is the wall clock.
+type RealClock struct{}
+
+func (RealClock) Now() time.Time                         { return time.Now() }
+func (RealClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (RealClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// InstantClock is a clock whose sleeps return at once, moving its time on
+// instead. Scripted runs use it: nobody is there to watch the typing, but
+// the blinking and typing effects still play out in full.
+type InstantClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewInstantClock creates an InstantClock starting at the current time.
+func NewInstantClock() *InstantClock {
+	return &InstantClock{now: time.Now()}
+}
+
+func (c *InstantClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *InstantClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (c *InstantClock) After(d time.Duration) <-chan time.Time {
+	c.Sleep(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.Now()
+	return ch
+}
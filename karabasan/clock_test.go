@@ -0,0 +1,67 @@
+package karabasan
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k.go/karabasan/clocktest"
+)
+
+func TestBlinkingCursorBlinksOncePerSecond(t *testing.T) {
+	for _, tt := range []struct {
+		duration time.Duration
+		blinks   int
+	}{
+		{0, 0},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+		{3 * time.Second, 3},
+	} {
+		var out strings.Builder
+		clock := clocktest.New()
+		s := NewTerminalSession(nil, strings.NewReader(""), &out)
+		s.Clock = clock
+		s.blinkingCursor(tt.duration)
+		if got := strings.Count(out.String(), "_"); got != tt.blinks {
+			t.Errorf("blinkingCursor(%v) blinked %d times, want %d", tt.duration, got, tt.blinks)
+		}
+		if want := time.Duration(tt.blinks) * time.Second; clock.Slept() != want {
+			t.Errorf("blinkingCursor(%v) slept %v, want %v", tt.duration, clock.Slept(), want)
+		}
+	}
+}
+
+func TestTypewriterTypesAtFixedSpeed(t *testing.T) {
+	var out strings.Builder
+	clock := clocktest.New()
+	s := NewTerminalSession(nil, strings.NewReader(""), &out)
+	s.Clock = clock
+	s.typewriterPrint("merhaba")
+	if out.String() != "merhaba\n" {
+		t.Errorf("typed %q, want %q", out.String(), "merhaba\n")
+	}
+	if want := 7 * 15 * time.Millisecond; clock.Slept() != want {
+		t.Errorf("typing took %v, want %v", clock.Slept(), want)
+	}
+}
+
+func TestInstantClockDoesNotWait(t *testing.T) {
+	var out strings.Builder
+	clock := NewInstantClock()
+	start := clock.Now()
+	s := NewTerminalSession(nil, strings.NewReader(""), &out)
+	s.Clock = clock
+	began := time.Now()
+	s.blinkingCursor(3 * time.Second)
+	s.typewriterPrint("merhaba")
+	if elapsed := time.Since(began); elapsed > time.Second {
+		t.Errorf("the effects took %v", elapsed)
+	}
+	if got := strings.Count(out.String(), "_"); got != 3 {
+		t.Errorf("blinked %d times, want 3", got)
+	}
+	if moved := clock.Now().Sub(start); moved < 3*time.Second {
+		t.Errorf("the clock moved on %v, want at least 3s", moved)
+	}
+}
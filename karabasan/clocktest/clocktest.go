@@ -0,0 +1,76 @@
+// Package clocktest provides a fake clock for testing code that times the
+// conversation through a karabasan.Clock.
+package clocktest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a karabasan.Clock whose time only moves when a test advances it or
+// the code under test sleeps; sleeping returns at once.
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []timer
+	slept  time.Duration
+}
+
+type timer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// New returns a Clock standing at the start of 2000.
+func New() *Clock {
+	return &Clock{now: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Sleep advances the clock by d instead of waiting.
+func (c *Clock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	c.slept += d
+	c.mu.Unlock()
+	c.Advance(d)
+}
+
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.timers = append(c.timers, timer{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward and fires the timers that came due.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.ch <- c.now
+	}
+	c.timers = pending
+}
+
+// Slept is the total time the code under test has slept.
+func (c *Clock) Slept() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.slept
+}
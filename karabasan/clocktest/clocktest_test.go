@@ -0,0 +1,23 @@
+package clocktest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAfter(t *testing.T) {
+	clock := New()
+	ch := clock.After(time.Second)
+	clock.Advance(999 * time.Millisecond)
+	select {
+	case <-ch:
+		t.Fatal("timer fired early")
+	default:
+	}
+	clock.Sleep(time.Millisecond)
+	select {
+	case <-ch:
+	default:
+		t.Fatal("timer did not fire when due")
+	}
+}
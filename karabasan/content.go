@@ -0,0 +1,284 @@
+package karabasan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"k.go/karabasan/turkish"
+)
+
+// --- Structs to match the JSON data structure ---
+type Content struct {
+	Greetings    []string     `json:"greetings"`
+	Jokes        []string     `json:"jokes"`
+	Laughs       []string     `json:"laughs"`
+	Swears       []string     `json:"swears"`
+	Proverbs     []string     `json:"proverbs"`
+	Markov       Markov       `json:"markov"`
+	Persona      Persona      `json:"persona"`
+	Difficulties []Difficulty `json:"difficulties"`
+	Scores       Scores       `json:"scores"`
+	Crash        string       `json:"crash"`
+	Serve        Serve        `json:"serve"`
+	Stages       Stages       `json:"stages"`
+
+	markov *markovChain // trained by LoadContent from Markov
+}
+
+// Scores holds the comments made against the user's previous best round.
+type Scores struct {
+	First  string `json:"first"`
+	Better string `json:"better"`
+	Same   string `json:"same"`
+	Worse  string `json:"worse"`
+}
+
+// Difficulty is a stage8 preset. MaxAttempts of 0 means unlimited guesses.
+// SuccessTiers holds the upper guess counts for the veryGood, good, average,
+// poor and veryPoor messages; anything above the last tier is terrible.
+// BlunderChance is how often Karabasan plays a random XOX move instead of
+// the minimax one.
+type Difficulty struct {
+	Name          string  `json:"name"`
+	Min           int     `json:"min"`
+	Max           int     `json:"max"`
+	MaxAttempts   int     `json:"maxAttempts"`
+	FarThreshold  int     `json:"farThreshold"`
+	SuccessTiers  []int   `json:"successTiers"`
+	BlunderChance float64 `json:"blunderChance"`
+}
+
+type Stages struct {
+	Stage1            Stage1            `json:"stage1"`
+	Stage2            Stage2            `json:"stage2"`
+	Stage3            Stage3            `json:"stage3"`
+	Stage4            Stage4            `json:"stage4"`
+	Stage5            Stage5            `json:"stage5"`
+	Stage6            Stage6            `json:"stage6"`
+	Stage7            Stage7            `json:"stage7"`
+	Stage8            Stage8            `json:"stage8"`
+	Hangman           Hangman           `json:"hangman"`
+	Stage9            Stage9            `json:"stage9"`
+	RockPaperScissors RockPaperScissors `json:"rockPaperScissors"`
+	XOX               XOX               `json:"xox"`
+	Stage10           Stage10           `json:"stage10"`
+	Chat              Chat              `json:"chat"`
+	Replay            Replay            `json:"replay"`
+}
+
+type Stage1 struct {
+	NamePrompt    string   `json:"namePrompt"`
+	LoginPrompt   string   `json:"loginPrompt"`
+	GenericLogin  string   `json:"genericLogin"`
+	GenericLogins []string `json:"genericLogins"`
+	Responses     struct {
+		Intro     string `json:"intro"`
+		ShortName string `json:"shortName"`
+		LongName  string `json:"longName"`
+	} `json:"responses"`
+}
+
+type Stage2 struct {
+	AgePrompt    string     `json:"agePrompt"`
+	AgeResponse  string     `json:"ageResponse"`
+	InvalidInput string     `json:"invalidInput"`
+	AgeRanges    []AgeRange `json:"ageRanges"`
+}
+
+type AgeRange struct {
+	Min  int    `json:"min"`
+	Max  int    `json:"max"`
+	Text string `json:"text"`
+	Yes  string `json:"yes,omitempty"`
+	No   string `json:"no,omitempty"`
+}
+
+type Stage3 struct {
+	HeightPrompt   string      `json:"heightPrompt"`
+	HeightResponse string      `json:"heightResponse"`
+	InvalidInput   string      `json:"invalidInput"`
+	HeightRanges   []RangeText `json:"heightRanges"`
+}
+
+type RangeText struct {
+	Min  int    `json:"min"`
+	Max  int    `json:"max"`
+	Text string `json:"text"`
+}
+
+type Stage4 struct {
+	WeightPrompt   string              `json:"weightPrompt"`
+	WeightResponse string              `json:"weightResponse"`
+	InvalidInput   string              `json:"invalidInput"`
+	WeightRanges   []RangeTextVariants `json:"weightRanges"`
+}
+
+type RangeTextVariants struct {
+	Min      int      `json:"min"`
+	Max      int      `json:"max"`
+	Text     string   `json:"text,omitempty"`
+	Variants []string `json:"variants,omitempty"`
+}
+
+type Stage5 struct {
+	Prompts  []Prompt `json:"prompts"`
+	Nickname Nickname `json:"nickname"`
+}
+
+type Prompt struct {
+	Text     string `json:"text"`
+	Yes      Lines  `json:"yes,omitempty"`
+	No       Lines  `json:"no,omitempty"`
+	Response string `json:"response,omitempty"`
+}
+
+// Lines is a content entry written in data.json either as one string or as
+// a list of strings.
+type Lines []string
+
+func (l *Lines) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*l = Lines{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("want a string or a list of strings: %w", err)
+	}
+	*l = many
+	return nil
+}
+
+// at returns line i, or "" when the content has fewer lines.
+func (l Lines) at(i int) string {
+	if i < 0 || i >= len(l) {
+		return ""
+	}
+	return l[i]
+}
+
+// prompt returns stage5 question i; ok is false when data.json has fewer.
+func (st Stage5) prompt(i int) (Prompt, bool) {
+	if i >= len(st.Prompts) {
+		return Prompt{}, false
+	}
+	return st.Prompts[i], true
+}
+
+type Stage6 struct {
+	JokeIntro string `json:"jokeIntro"`
+}
+
+type Stage7 struct {
+	HometownPrompt string            `json:"hometownPrompt"`
+	VowelResponses map[string]string `json:"vowelResponses"`
+	Conclusion     string            `json:"conclusion"`
+}
+
+type Stage8 struct {
+	DifficultyPrompt  string `json:"difficultyPrompt"`
+	UnknownDifficulty string `json:"unknownDifficulty"`
+	Intro             string `json:"intro"`
+	AttemptsInfo      string `json:"attemptsInfo"`
+	GuessPrompt       string `json:"guessPrompt"`
+	InvalidGuess      string `json:"invalidGuess"`
+	TooLow            string `json:"tooLow"`
+	TooLowFar         string `json:"tooLowFar"`
+	TooHigh           string `json:"tooHigh"`
+	TooHighFar        string `json:"tooHighFar"`
+	OutOfBounds       string `json:"outOfBounds"`
+	OutOfAttempts     string `json:"outOfAttempts"`
+	Success           struct {
+		VeryGood string `json:"veryGood"`
+		Good     string `json:"good"`
+		Average  string `json:"average"`
+		Poor     string `json:"poor"`
+		VeryPoor string `json:"veryPoor"`
+		Terrible string `json:"terrible"`
+	} `json:"success"`
+}
+
+type Stage9 struct {
+	Prompts   []string `json:"prompts"`
+	Responses struct {
+		Win      string `json:"win"`
+		Cheating string `json:"cheating"`
+		Equal    string `json:"equal"`
+		// Unsolved is said when the user did not solve stage8.
+		Unsolved string `json:"unsolved"`
+	} `json:"responses"`
+}
+
+type Stage10 struct {
+	JokeIntro  string `json:"jokeIntro"`
+	ExitPrompt string `json:"exitPrompt"`
+}
+
+// Replay is the "play again?" question asked after stage10.
+type Replay struct {
+	Prompt        string         `json:"prompt"`
+	RestartPrompt string         `json:"restartPrompt"`
+	UnknownStage  string         `json:"unknownStage"`
+	Choices       []ReplayChoice `json:"choices"`
+}
+
+// Serve holds what the network front ends say outside the conversation.
+type Serve struct {
+	Busy     string `json:"busy"`
+	Idle     string `json:"idle"`
+	Shutdown string `json:"shutdown"`
+}
+
+// ReplayChoice is a named place to restart the conversation from.
+type ReplayChoice struct {
+	Name  string `json:"name"`
+	Stage string `json:"stage"`
+}
+
+// LoadContent reads the JSON file at path and unmarshals it into a Content struct.
+func LoadContent(path string) (*Content, error) {
+	byteValue, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: please make sure the file exists and is in the same directory: %w", path, err)
+	}
+	var content Content
+	if err := json.Unmarshal(byteValue, &content); err != nil {
+		return nil, fmt.Errorf("unmarshaling %s: %w", path, err)
+	}
+	if err := checkTemplates(reflect.ValueOf(content), "content"); err != nil {
+		return nil, fmt.Errorf("loading %s: %w", path, err)
+	}
+	if err := content.Stages.Hangman.check(); err != nil {
+		return nil, fmt.Errorf("loading %s: %w", path, err)
+	}
+	if err := content.Stages.RockPaperScissors.check(); err != nil {
+		return nil, fmt.Errorf("loading %s: %w", path, err)
+	}
+	if err := content.trainMarkov(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("loading %s: %w", path, err)
+	}
+	return &content, nil
+}
+
+// FindDifficulty looks up a difficulty preset by name, ignoring case.
+func (c *Content) FindDifficulty(name string) (Difficulty, bool) {
+	for _, d := range c.Difficulties {
+		if turkish.EqualFold(d.Name, name) {
+			return d, true
+		}
+	}
+	return Difficulty{}, false
+}
+
+// DifficultyNames lists the preset names in content order, e.g. for flag errors.
+func (c *Content) DifficultyNames() []string {
+	names := make([]string, 0, len(c.Difficulties))
+	for _, d := range c.Difficulties {
+		names = append(names, d.Name)
+	}
+	return names
+}
@@ -0,0 +1,49 @@
+package karabasan
+
+// ProtocolVersion is the version of the event protocol. It goes up when
+// an event changes in a way that breaks existing clients; new event types
+// and fields do not change it.
+const ProtocolVersion = 1
+
+// The event types of the protocol.
+const (
+	EventHello    = "hello"    // first event: the protocol version and the seed
+	EventStage    = "stage"    // a new stage starts
+	EventSay      = "say"      // Karabasan says something
+	EventThinking = "thinking" // Karabasan thinks before answering, for ms milliseconds
+	EventPause    = "pause"    // a dramatic pause of ms milliseconds
+	EventPrompt   = "prompt"   // Karabasan waits for an input of the expected kind
+	EventEnd      = "end"      // the conversation is over
+	EventError    = "error"    // an input line could not be used
+)
+
+// InputKind is what a prompt expects the user to answer.
+type InputKind string
+
+const (
+	InputText   InputKind = "text"
+	InputNumber InputKind = "number"
+	InputYesNo  InputKind = "yes/no" // "e" for yes, anything else for no
+)
+
+// Event is one step of the conversation as the protocol reports it. Every
+// event carries the protocol version and the id of the running stage.
+type Event struct {
+	Version int       `json:"v"`
+	Type    string    `json:"type"`
+	Stage   StageID   `json:"stage,omitempty"`
+	Text    string    `json:"text,omitempty"`
+	Expect  InputKind `json:"expect,omitempty"`
+	Millis  int64     `json:"ms,omitempty"`
+	Seed    uint64    `json:"seed,omitempty"`
+}
+
+// emit reports an event to s.Events, if it is set.
+func (s *Session) emit(e Event) {
+	if s.Events == nil {
+		return
+	}
+	e.Version = ProtocolVersion
+	e.Stage = s.Stage
+	s.Events(e)
+}
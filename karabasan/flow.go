@@ -0,0 +1,141 @@
+package karabasan
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k.go/karabasan/turkish"
+)
+
+// StageID names a step of the conversation. The ids match the stage keys
+// in data.json so that restarts can refer to them.
+type StageID string
+
+const (
+	stage0ID            StageID = "stage0"
+	stage1ID            StageID = "stage1"
+	stage2ID            StageID = "stage2"
+	stage3ID            StageID = "stage3"
+	stage4ID            StageID = "stage4"
+	stage5ID            StageID = "stage5"
+	stage6ID            StageID = "stage6"
+	stage7ID            StageID = "stage7"
+	stage8ID            StageID = "stage8"
+	hangmanID           StageID = "hangman"
+	stage9ID            StageID = "stage9"
+	rockPaperScissorsID StageID = "rockPaperScissors"
+	xoxID               StageID = "xox"
+	stage10ID           StageID = "stage10"
+	chatID              StageID = "chat"
+	replayID            StageID = "replay"
+
+	// stageEnd is returned by a stage to finish the session.
+	stageEnd StageID = ""
+
+	// FirstStage is where a new conversation starts.
+	FirstStage = stage0ID
+)
+
+// stageOrder lists the stage ids in conversation order.
+var stageOrder = []StageID{
+	stage0ID, stage1ID, stage2ID, stage3ID, stage4ID, stage5ID, stage6ID, stage7ID,
+	stage8ID, hangmanID, stage9ID, rockPaperScissorsID, xoxID, stage10ID, chatID, replayID,
+}
+
+// stageTable maps every stage id to the method that runs it. Each stage
+// returns the id of the stage to continue with.
+var stageTable = map[StageID]func(*Session) StageID{
+	stage0ID:            (*Session).stage0,
+	stage1ID:            (*Session).stage1,
+	stage2ID:            (*Session).stage2,
+	stage3ID:            (*Session).stage3,
+	stage4ID:            (*Session).stage4,
+	stage5ID:            (*Session).stage5,
+	stage6ID:            (*Session).stage6,
+	stage7ID:            (*Session).stage7,
+	stage8ID:            (*Session).stage8,
+	hangmanID:           (*Session).stageHangman,
+	stage9ID:            (*Session).stage9,
+	rockPaperScissorsID: (*Session).stageRockPaperScissors,
+	xoxID:               (*Session).stageXOX,
+	stage10ID:           (*Session).stage10,
+	chatID:              (*Session).stageChat,
+	replayID:            (*Session).stageReplay,
+}
+
+// ErrInputClosed is raised by readLine when the user's input ends, and turned
+// into Run's return value so that a hung-up session unwinds from any stage.
+var ErrInputClosed = errors.New("input closed")
+
+// Run drives the conversation from the start stage until a stage returns
+// stageEnd. It returns ErrInputClosed when the input ran out before that.
+func (s *Session) Run(start StageID) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if r != ErrInputClosed {
+				panic(r)
+			}
+			err = ErrInputClosed
+		}
+		s.emit(Event{Type: EventEnd})
+	}()
+	s.emit(Event{Type: EventHello, Seed: s.Seed})
+	for id := start; id != stageEnd; {
+		stage, ok := stageTable[id]
+		if !ok {
+			return fmt.Errorf("unknown stage %q", id)
+		}
+		s.Stage = id
+		s.emit(Event{Type: EventStage})
+		id = stage(s)
+	}
+	return nil
+}
+
+// restartStage resolves a restart choice from data.json or a raw stage id.
+func (s *Session) restartStage(input string) (StageID, bool) {
+	if input == "" {
+		return stage1ID, true
+	}
+	for _, choice := range s.content.Stages.Replay.Choices {
+		if turkish.EqualFold(choice.Name, input) {
+			return StageID(choice.Stage), true
+		}
+	}
+	for _, id := range stageOrder {
+		if turkish.EqualFold(string(id), input) {
+			return id, true
+		}
+	}
+	return stageEnd, false
+}
+
+// stageReplay asks whether to play again and where to start over.
+func (s *Session) stageReplay() StageID {
+	replay := s.content.Stages.Replay
+	s.userPrompt(s.render(replay.Prompt, s.UserName))
+	if turkish.Lower(s.readYesNo()) != "e" {
+		s.userPrompt(s.render(s.content.Stages.Stage10.ExitPrompt))
+		s.readLine()
+		return stageEnd
+	}
+
+	var names []string
+	for _, choice := range replay.Choices {
+		names = append(names, choice.Name)
+	}
+	for {
+		s.userPrompt(s.render(replay.RestartPrompt, strings.Join(names, "/")))
+		if id, ok := s.restartStage(s.readLine()); ok {
+			// Difficulty stays as picked; a round that starts past stage8
+			// has no stage8 result to compare with or to save.
+			s.Score = 0
+			s.Round = ScoreEntry{}
+			s.skippedStage8 = slices.Index(stageOrder, id) > slices.Index(stageOrder, stage8ID)
+			return id
+		}
+		s.aiResponse(s.render(replay.UnknownStage))
+	}
+}
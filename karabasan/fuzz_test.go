@@ -0,0 +1,35 @@
+package karabasan
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// FuzzStages drives every stage with random answers. Whatever the user
+// types, a session may only end normally or run out of input.
+func FuzzStages(f *testing.F) {
+	content, err := LoadContent("../data.json")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for i := range stageOrder {
+		f.Add(uint8(i), uint64(1), "Ali", "Ali\n30\n175\n70\ne\nh\ne\nh\nAnkara\n")
+	}
+	// Regressions: a two letter name with a vowel second, and stage9 answers
+	// that leave no number to guess.
+	f.Add(uint8(5), uint64(183), "Ai", strings.Repeat("e\n", 10))
+	f.Add(uint8(10), uint64(1), "Ali", strings.Repeat("d\n", 20))
+	f.Add(uint8(10), uint64(1), "Ali", strings.Repeat("y\n", 20))
+	f.Add(uint8(10), uint64(3), "Ali", "y\nd\ny\nd\ny\nd\ny\nd\ny\nd\n")
+
+	f.Fuzz(func(t *testing.T, stage uint8, seed uint64, name, input string) {
+		s := NewTerminalSession(content, strings.NewReader(input), io.Discard)
+		s.Plain = true
+		s.SetSeed(seed)
+		s.UserName = name
+		if err := s.Run(stageOrder[int(stage)%len(stageOrder)]); err != nil && err != ErrInputClosed {
+			t.Fatalf("Run: %v", err)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package karabasan
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files under testdata/")
+
+// scriptOptions are the "# key: value" directives at the top of a test script.
+type scriptOptions struct {
+	seed       uint64
+	stage      StageID
+	user       string
+	login      string
+	difficulty string
+	score      int
+}
+
+// parseScriptOptions reads the directives of a test script. Scripts start
+// at stage0 with seed 1 unless told otherwise.
+func parseScriptOptions(t *testing.T, script string) scriptOptions {
+	t.Helper()
+	opts := scriptOptions{seed: 1, stage: stage0ID}
+	for _, line := range strings.Split(script, "\n") {
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "#"), ":")
+		if !strings.HasPrefix(line, "#") || !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		var err error
+		switch strings.TrimSpace(key) {
+		case "seed":
+			opts.seed, err = strconv.ParseUint(value, 10, 64)
+		case "stage":
+			opts.stage = StageID(value)
+		case "user":
+			opts.user = value
+		case "login":
+			opts.login = value
+		case "difficulty":
+			opts.difficulty = value
+		case "score":
+			opts.score, err = strconv.Atoi(value)
+		}
+		if err != nil {
+			t.Fatalf("bad directive %q: %v", line, err)
+		}
+	}
+	return opts
+}
+
+// runScript plays a script the way "karabasan --script --plain" does and
+// returns the transcript.
+func runScript(t *testing.T, content *Content, script string) string {
+	t.Helper()
+	opts := parseScriptOptions(t, script)
+	var out bytes.Buffer
+	s := NewTerminalSession(content, strings.NewReader(ScriptAnswers(script)), &out)
+	s.Plain = true
+	s.Echo = &out
+	s.SetSeed(opts.seed)
+	s.UserName = opts.user
+	s.LoginName = opts.login
+	s.Difficulty = opts.difficulty
+	s.Score = opts.score
+	s.ScoresPath = filepath.Join(t.TempDir(), "scores.json")
+	if err := s.Run(opts.stage); err != nil && err != ErrInputClosed {
+		t.Fatalf("Run: %v", err)
+	}
+	return out.String()
+}
+
+func TestGolden(t *testing.T) {
+	content, err := LoadContent("../data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scripts, err := filepath.Glob(filepath.Join("testdata", "*.script"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) == 0 {
+		t.Fatal("no scripts under testdata/")
+	}
+	for _, path := range scripts {
+		name := strings.TrimSuffix(filepath.Base(path), ".script")
+		t.Run(name, func(t *testing.T) {
+			script, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := runScript(t, content, string(script))
+			golden := strings.TrimSuffix(path, ".script") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("transcript differs from %s (run go test -update to accept):\n%s", golden, got)
+			}
+		})
+	}
+}
+
+// TestSameSeedSameConversation checks that a seed and a script fully decide
+// the conversation, which the golden files rely on.
+func TestSameSeedSameConversation(t *testing.T) {
+	content, err := LoadContent("../data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := "# seed: 99\nAli\n30\n175\n70\ne\nh\ne\nh\ne\nh\ne\nh\nAnkara\n"
+	if a, b := runScript(t, content, script), runScript(t, content, script); a != b {
+		t.Errorf("two runs with the same seed differ:\n%s\n---\n%s", a, b)
+	}
+}
@@ -0,0 +1,165 @@
+package karabasan
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"k.go/karabasan/turkish"
+)
+
+// Hangman is the content of the adam asmaca stage.
+type Hangman struct {
+	Intro        string         `json:"intro"`
+	GuessPrompt  string         `json:"guessPrompt"`
+	InvalidGuess string         `json:"invalidGuess"`
+	Repeated     string         `json:"repeated"`
+	Correct      []string       `json:"correct"`
+	Wrong        string         `json:"wrong"`
+	WrongWord    string         `json:"wrongWord"`
+	Win          string         `json:"win"`
+	Lose         string         `json:"lose"`
+	Gallows      []string       `json:"gallows"`
+	Categories   []WordCategory `json:"categories"`
+}
+
+// WordCategory is a named group of words for the adam asmaca stage.
+type WordCategory struct {
+	Name  string   `json:"name"`
+	Words []string `json:"words"`
+}
+
+// check reports the hangman content the stage cannot be played with: no
+// gallows to hang on, no categories, or a category without words. Every
+// word has to be made of Turkish letters, or it could never be guessed.
+func (h Hangman) check() error {
+	if len(h.Gallows) < 2 {
+		return fmt.Errorf("hangman.gallows: %d drawings, need at least 2", len(h.Gallows))
+	}
+	if len(h.Categories) == 0 {
+		return errors.New("hangman.categories: no categories")
+	}
+	for i, category := range h.Categories {
+		if len(category.Words) == 0 {
+			return fmt.Errorf("hangman.categories[%d] %q: no words", i, category.Name)
+		}
+		for _, word := range category.Words {
+			if !isTurkishWord(turkish.Lower(word)) {
+				return fmt.Errorf("hangman.categories[%d] %q: %q is not a word of Turkish letters", i, category.Name, word)
+			}
+		}
+	}
+	return nil
+}
+
+// turkishAlphabet lists the 29 letters; ç, ğ, ı, ö, ş and ü are letters of their own.
+const turkishAlphabet = "abcçdefgğhıijklmnoöprsştuüvyz"
+
+// isTurkishWord reports whether s is made only of Turkish letters.
+func isTurkishWord(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune(turkishAlphabet, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// maskWord shows the guessed letters of word and an underscore for the rest.
+func maskWord(word string, guessed map[rune]bool) string {
+	var parts []string
+	for _, r := range word {
+		if guessed[r] {
+			parts = append(parts, turkish.Upper(string(r)))
+		} else {
+			parts = append(parts, "_")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// wordSolved reports whether every letter of word has been guessed.
+func wordSolved(word string, guessed map[rune]bool) bool {
+	for _, r := range word {
+		if !guessed[r] {
+			return false
+		}
+	}
+	return true
+}
+
+// drawGallows prints the gallows for the given number of wrong guesses and the masked word.
+func (s *Session) drawGallows(wrong int, masked string) {
+	gallows := s.content.Stages.Hangman.Gallows
+	if wrong >= len(gallows) {
+		wrong = len(gallows) - 1
+	}
+	s.centerPrint(padBlock(gallows[wrong]))
+	s.centerPrint(masked)
+}
+
+// stageHangman is adam asmaca: the user guesses a Turkish word letter by letter.
+func (s *Session) stageHangman() StageID {
+	hangman := s.content.Stages.Hangman
+	category := hangman.Categories[s.randomInt(len(hangman.Categories))]
+	word := turkish.Lower(category.Words[s.randomInt(len(category.Words))])
+	maxWrong := len(hangman.Gallows) - 1
+	guessed := map[rune]bool{}
+	wrong := 0
+
+	s.aiResponse(s.render(hangman.Intro, s.UserName, category.Name, utf8.RuneCountInString(word)))
+	for {
+		s.drawGallows(wrong, maskWord(word, guessed))
+		if wordSolved(word, guessed) {
+			s.aiResponse(s.render(hangman.Win, turkish.Upper(word), wrong))
+			break
+		}
+		if wrong >= maxWrong {
+			s.aiResponse(s.render(hangman.Lose, turkish.Upper(word)))
+			s.laugh()
+			break
+		}
+
+		s.userPrompt(s.render(hangman.GuessPrompt))
+		input := turkish.Lower(s.readLine())
+		if !isTurkishWord(input) {
+			s.aiResponse(s.render(hangman.InvalidGuess))
+			continue
+		}
+
+		if utf8.RuneCountInString(input) > 1 {
+			if input == word {
+				for _, r := range word {
+					guessed[r] = true
+				}
+				continue
+			}
+			wrong++
+			s.aiResponse(s.render(hangman.WrongWord, turkish.Upper(input), maxWrong-wrong))
+			s.aiResponse(s.render(s.randomLine(s.content.Swears)))
+			continue
+		}
+
+		letter, _ := utf8.DecodeRuneInString(input)
+		if guessed[letter] {
+			s.aiResponse(s.render(hangman.Repeated, turkish.Upper(input)))
+			continue
+		}
+		guessed[letter] = true
+		if strings.ContainsRune(word, letter) {
+			s.aiResponse(s.render(s.randomLine(hangman.Correct), turkish.Upper(input)))
+		} else {
+			wrong++
+			s.aiResponse(s.render(hangman.Wrong, turkish.Upper(input), maxWrong-wrong))
+			s.aiResponse(s.render(s.randomLine(s.content.Swears)))
+		}
+	}
+
+	s.Round.HangmanSolved = wordSolved(word, guessed)
+	s.Round.HangmanWrong = wrong
+	return stage9ID
+}
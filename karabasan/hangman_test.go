@@ -0,0 +1,43 @@
+package karabasan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHangmanContentChecked(t *testing.T) {
+	gallows := []string{"|", "|o"}
+	words := []WordCategory{{Name: "hayvanlar", Words: []string{"KEDİ", "ağaç"}}}
+	for name, tt := range map[string]struct {
+		hangman Hangman
+		err     string
+	}{
+		"playable":      {Hangman{Gallows: gallows, Categories: words}, ""},
+		"no gallows":    {Hangman{Categories: words}, "hangman.gallows"},
+		"one drawing":   {Hangman{Gallows: gallows[:1], Categories: words}, "hangman.gallows"},
+		"no categories": {Hangman{Gallows: gallows}, "no categories"},
+		"no words":      {Hangman{Gallows: gallows, Categories: []WordCategory{{Name: "boş"}}}, `"boş": no words`},
+		"not a word":    {Hangman{Gallows: gallows, Categories: []WordCategory{{Name: "x", Words: []string{"qwerty"}}}}, `"qwerty"`},
+	} {
+		err := tt.hangman.check()
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: check() = %v, want an error with %q", name, err, tt.err)
+		}
+	}
+
+	// LoadContent refuses such a file instead of panicking in the stage.
+	data, err := os.ReadFile("../data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "data.json")
+	data = []byte(strings.Replace(string(data), `"categories": [`, `"categories": [], "unused": [`, 1))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadContent(path); err == nil || !strings.Contains(err.Error(), "no categories") {
+		t.Errorf("LoadContent with no hangman categories = %v", err)
+	}
+}
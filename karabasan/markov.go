@@ -0,0 +1,202 @@
+package karabasan
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Markov configures the jokes and insults Karabasan makes up. A word-level
+// chain looking back Order words is trained on the jokes, swears and
+// proverbs and on the extra Corpora: text files, relative to data.json,
+// holding one entry per paragraph. Ratio of the jokes sayJoke tells, of
+// MinWords to MaxWords words, are made up, and as many of the insults
+// swear hurls: those start like one of the swears and run to at most
+// SwearWords words. An Order of 0 turns the generator off, a SwearWords of
+// 0 the insults.
+type Markov struct {
+	Order      int      `json:"order"`
+	Ratio      float64  `json:"ratio"`
+	MinWords   int      `json:"minWords"`
+	MaxWords   int      `json:"maxWords"`
+	SwearWords int      `json:"swearWords"`
+	Attempts   int      `json:"attempts"`
+	Corpora    []string `json:"corpora,omitempty"`
+}
+
+// markovEnd marks the end of an entry in the chain; line breaks are words
+// of their own so that made-up jokes keep the "...\n" cadence.
+const markovEnd = ""
+
+// markovChain maps the last Order words to every word that followed them.
+type markovChain struct {
+	order int
+	next  map[string][]string
+	known map[string]bool // the training entries, to reject exact copies
+}
+
+// markovWords splits an entry into words, with "\n" for each line break.
+func markovWords(entry string) []string {
+	var words []string
+	for i, line := range strings.Split(strings.TrimSpace(entry), "\n") {
+		if i > 0 {
+			words = append(words, "\n")
+		}
+		words = append(words, strings.Fields(line)...)
+	}
+	return words
+}
+
+// joinMarkov puts words back together the way they were split.
+func joinMarkov(words []string) string {
+	var b strings.Builder
+	for i, word := range words {
+		if i > 0 && word != "\n" && words[i-1] != "\n" {
+			b.WriteByte(' ')
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
+
+func markovKey(words []string) string {
+	return strings.Join(words, "\x00")
+}
+
+// newMarkovChain trains a chain of the given order on entries.
+func newMarkovChain(order int, entries []string) *markovChain {
+	m := &markovChain{order: order, next: map[string][]string{}, known: map[string]bool{}}
+	for _, entry := range entries {
+		words := markovWords(entry)
+		if len(words) == 0 {
+			continue
+		}
+		m.known[joinMarkov(words)] = true
+		state := make([]string, order)
+		for _, word := range append(words, markovEnd) {
+			key := markovKey(state)
+			m.next[key] = append(m.next[key], word)
+			state = append(state[1:], word)
+		}
+	}
+	return m
+}
+
+// countWords counts the words of words, leaving out the line breaks.
+func countWords(words []string) int {
+	n := 0
+	for _, word := range words {
+		if word != "\n" {
+			n++
+		}
+	}
+	return n
+}
+
+// generate walks the chain from start, the first words of an entry, or
+// from the start of one when it is empty, stopping at the end of an entry
+// or after maxWords words.
+func (m *markovChain) generate(rng *rand.Rand, start []string, maxWords int) []string {
+	words := slices.Clone(start)
+	state := append(make([]string, m.order), start...)
+	state = state[len(state)-m.order:]
+	for n := countWords(words); n < maxWords; {
+		choices := m.next[markovKey(state)]
+		if len(choices) == 0 {
+			break
+		}
+		word := choices[rng.IntN(len(choices))]
+		if word == markovEnd {
+			break
+		}
+		words = append(words, word)
+		state = append(state[1:], word)
+		if word != "\n" {
+			n++
+		}
+	}
+	return words
+}
+
+// makeUp generates an entry starting with start that is not a copy of the
+// training data and has at least minWords and at most maxWords words; ok
+// is false when none of the attempts gave one.
+func (m *markovChain) makeUp(rng *rand.Rand, start []string, minWords, maxWords, attempts int) (entry string, ok bool) {
+	for range attempts {
+		// One word more than allowed tells a cut-off entry from one that
+		// ended at exactly maxWords.
+		words := m.generate(rng, start, maxWords+1)
+		for len(words) > 0 && words[len(words)-1] == "\n" {
+			words = words[:len(words)-1]
+		}
+		if n := countWords(words); n < minWords || n > maxWords {
+			continue
+		}
+		if entry := joinMarkov(words); !m.known[entry] {
+			return entry, true
+		}
+	}
+	return "", false
+}
+
+// readCorpus reads a corpus file into its entries, one per paragraph.
+func readCorpus(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []string
+	for _, entry := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n\n") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// trainMarkov builds the joke and insult generator from the content and the corpora,
+// whose paths are relative to dir.
+func (c *Content) trainMarkov(dir string) error {
+	if c.Markov.Order <= 0 {
+		return nil
+	}
+	entries := slices.Concat(c.Jokes, c.Swears, c.Proverbs)
+	for _, corpus := range c.Markov.Corpora {
+		if !filepath.IsAbs(corpus) {
+			corpus = filepath.Join(dir, corpus)
+		}
+		more, err := readCorpus(corpus)
+		if err != nil {
+			return fmt.Errorf("reading corpus: %w", err)
+		}
+		entries = append(entries, more...)
+	}
+	c.markov = newMarkovChain(c.Markov.Order, entries)
+	return nil
+}
+
+// madeUpJoke makes up a joke from the content pack, if the content has a
+// generator and it comes up with something new.
+func (s *Session) madeUpJoke() (string, bool) {
+	m := s.content.Markov
+	if s.content.markov == nil || m.Ratio <= 0 || s.rng.Float64() >= m.Ratio {
+		return "", false
+	}
+	return s.content.markov.makeUp(s.rng, nil, m.MinWords, m.MaxWords, max(m.Attempts, 1))
+}
+
+// madeUpSwear makes up an insult that starts like one of the swears, at the
+// same ratio as the jokes.
+func (s *Session) madeUpSwear() (string, bool) {
+	m := s.content.Markov
+	swears := s.content.Swears
+	if s.content.markov == nil || m.SwearWords <= 0 || len(swears) == 0 || m.Ratio <= 0 || s.rng.Float64() >= m.Ratio {
+		return "", false
+	}
+	start := markovWords(swears[s.rng.IntN(len(swears))])
+	start = start[:min(len(start), s.content.markov.order)]
+	return s.content.markov.makeUp(s.rng, start, countWords(start)+1, m.SwearWords, max(m.Attempts, 1))
+}
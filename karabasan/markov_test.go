@@ -0,0 +1,179 @@
+package karabasan
+
+import (
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMarkovWordsKeepLineBreaks(t *testing.T) {
+	entry := "adamın biri soğuk çay istemiş...\nçaycı  çayı getirmiş..."
+	words := markovWords(entry)
+	if want := []string{"adamın", "biri", "soğuk", "çay", "istemiş...", "\n", "çaycı", "çayı", "getirmiş..."}; !slices.Equal(words, want) {
+		t.Errorf("markovWords = %q, want %q", words, want)
+	}
+	if got := joinMarkov(words); got != "adamın biri soğuk çay istemiş...\nçaycı çayı getirmiş..." {
+		t.Errorf("joinMarkov = %q", got)
+	}
+}
+
+func TestMarkovMakesUpNewEntries(t *testing.T) {
+	content, err := LoadContent("../data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := content.markov
+	if m == nil {
+		t.Fatal("data.json has no joke generator")
+	}
+	var training []string
+	for _, entry := range slices.Concat(content.Jokes, content.Swears, content.Proverbs) {
+		training = append(training, joinMarkov(markovWords(entry)))
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	made := 0
+	for range 200 {
+		entry, ok := m.makeUp(rng, nil, 8, 40, 5)
+		if !ok {
+			continue
+		}
+		made++
+		if slices.Contains(training, entry) {
+			t.Errorf("made up a copy of the content:\n%s", entry)
+		}
+		if n := len(strings.Fields(entry)); n < 8 || n >= 40 {
+			t.Errorf("made up %d words, want 8 to 39:\n%s", n, entry)
+		}
+		if strings.HasPrefix(entry, "\n") || strings.HasSuffix(entry, "\n") || strings.Contains(entry, " \n") {
+			t.Errorf("badly broken lines in %q", entry)
+		}
+	}
+	if made == 0 {
+		t.Error("nothing was made up")
+	}
+}
+
+func TestMarkovIsSeedable(t *testing.T) {
+	m := newMarkovChain(1, []string{"a b c...\nd e", "a c b...\ne d", "b a...\nc d e"})
+	a := rand.New(rand.NewPCG(9, 9))
+	b := rand.New(rand.NewPCG(9, 9))
+	for range 20 {
+		x, okX := m.makeUp(a, nil, 2, 20, 3)
+		y, okY := m.makeUp(b, nil, 2, 20, 3)
+		if x != y || okX != okY {
+			t.Fatalf("the same seed made up %q and %q", x, y)
+		}
+	}
+}
+
+func TestMarkovReadsCorpora(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile("../data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "data.json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	content, err := LoadContent(filepath.Join(dir, "data.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	corpus := "Nasreddin Hoca göle maya çalmış...\n'ya tutarsa' demiş!\r\n\r\n\nhoca eşeğe ters binmiş...\n"
+	if err := os.WriteFile(filepath.Join(dir, "hoca.txt"), []byte(corpus), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	content.Markov.Corpora = []string{"hoca.txt"}
+	if err := content.trainMarkov(dir); err != nil {
+		t.Fatal(err)
+	}
+	if !content.markov.known["hoca eşeğe ters binmiş..."] || !content.markov.known["Nasreddin Hoca göle maya çalmış...\n'ya tutarsa' demiş!"] {
+		t.Errorf("the corpus entries were not trained on: %v", content.markov.known)
+	}
+
+	content.Markov.Corpora = []string{"yok.txt"}
+	if err := content.trainMarkov(dir); err == nil {
+		t.Error("a missing corpus was not reported")
+	}
+}
+
+func TestSayJokeMixesInMadeUpJokes(t *testing.T) {
+	content, err := LoadContent("../data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ratio, want := range map[float64]bool{0: false, 1: true} {
+		content.Markov.Ratio = ratio
+		var out strings.Builder
+		s := NewTerminalSession(content, strings.NewReader(""), &out)
+		s.Plain = true
+		s.SetSeed(4)
+		pool := 0
+		for range 30 {
+			out.Reset()
+			s.sayJoke()
+			for _, joke := range content.Jokes {
+				if strings.Contains(out.String(), strings.Split(joke, "\n")[0]) && strings.Contains(out.String(), joke[strings.LastIndex(joke, "\n")+1:]) {
+					pool++
+					break
+				}
+			}
+		}
+		if made := pool < 30; made != want {
+			t.Errorf("ratio %v: %d of 30 jokes came from the pool", ratio, pool)
+		}
+	}
+}
+
+func TestMarkovWordBounds(t *testing.T) {
+	// Besides the copies, this chain only makes up "a b y" and "x b c".
+	m := newMarkovChain(1, []string{"a b c", "x b y"})
+	rng := rand.New(rand.NewPCG(3, 4))
+	for range 20 {
+		entry, ok := m.makeUp(rng, nil, 3, 3, 10)
+		if !ok {
+			t.Fatal("no entry of exactly maxWords words was made up")
+		}
+		if entry != "a b y" && entry != "x b c" {
+			t.Fatalf("made up %q", entry)
+		}
+		if entry, ok := m.makeUp(rng, nil, 1, 2, 10); ok {
+			t.Fatalf("made up %q, longer than 2 words", entry)
+		}
+	}
+}
+
+func TestMarkovMakesUpInsults(t *testing.T) {
+	content, err := LoadContent("../data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content.Markov.Ratio = 1
+	s := NewTerminalSession(content, strings.NewReader(""), io.Discard)
+	s.SetSeed(1)
+	made := 0
+	for range 50 {
+		insult, ok := s.madeUpSwear()
+		if !ok {
+			continue
+		}
+		made++
+		words := markovWords(insult)
+		if n := countWords(words); n > content.Markov.SwearWords {
+			t.Errorf("%q has %d words", insult, n)
+		}
+		if !slices.ContainsFunc(content.Swears, func(swear string) bool { return markovWords(swear)[0] == words[0] }) {
+			t.Errorf("%q does not start like a swear", insult)
+		}
+		if slices.Contains(content.Swears, insult) {
+			t.Errorf("%q is a copy of a swear", insult)
+		}
+	}
+	if made == 0 {
+		t.Error("no insult was made up")
+	}
+}
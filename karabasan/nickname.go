@@ -0,0 +1,98 @@
+package karabasan
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"k.go/karabasan/turkish"
+)
+
+// Nickname is the content of stage5's nickname question. A nickname is the
+// user's first name cut after its first syllable, taking along the first
+// consonant of the next one when the two may end a word together, with the
+// first of Suffixes that fits the cut: Meh-met→Mem→Memoş, Ay-şe→Ayş→Ayşoş,
+// Mus-ta-fa→Must→Musti. Exceptions, keyed by the lowercased first name,
+// stand for names the rules get wrong.
+type Nickname struct {
+	Text string `json:"text"`
+	Yes  string `json:"yes"`
+	No   string `json:"no"`
+
+	// Weak consonants are dropped from the end of the first syllable, so
+	// that Meh-met is cut to Mem.
+	Weak string `json:"weak"`
+	// Clusters are the consonant pairs a cut name may end in, such as the
+	// st of Must and the yş of Ayş.
+	Clusters   []string          `json:"clusters"`
+	Suffixes   []NicknameSuffix  `json:"suffixes"`
+	Exceptions map[string]string `json:"exceptions"`
+}
+
+// NicknameSuffix goes on a cut name ending in one of Endings, whose last
+// vowel is one of Vowels, and whose whole name has Syllables syllables;
+// the conditions left out always hold.
+type NicknameSuffix struct {
+	Endings   []string `json:"endings,omitempty"`
+	Vowels    string   `json:"vowels,omitempty"`
+	Syllables int      `json:"syllables,omitempty"`
+	Suffix    string   `json:"suffix"`
+}
+
+// fits reports whether the suffix goes on stem, cut from a name of the
+// given number of syllables.
+func (ns NicknameSuffix) fits(stem string, syllables int) bool {
+	if len(ns.Endings) > 0 && !slices.ContainsFunc(ns.Endings, func(e string) bool { return strings.HasSuffix(stem, e) }) {
+		return false
+	}
+	if ns.Vowels != "" {
+		if vowel, ok := turkish.LastVowel(stem); !ok || !strings.ContainsRune(ns.Vowels, vowel) {
+			return false
+		}
+	}
+	return ns.Syllables == 0 || ns.Syllables == syllables
+}
+
+// of returns the nickname for name, or "" when there is none to be made,
+// as for a name without vowels.
+func (n Nickname) of(name string) string {
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return ""
+	}
+	first := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		return -1
+	}, turkish.Lower(words[0]))
+	if nickname, ok := n.Exceptions[first]; ok {
+		return nickname
+	}
+	if _, ok := turkish.LastVowel(first); !ok {
+		return ""
+	}
+	syllables := turkish.Syllables(first)
+	stem := syllables[0]
+	if len(syllables) > 1 {
+		stem = strings.TrimRight(stem, n.Weak)
+		next, _ := utf8.DecodeRuneInString(syllables[1])
+		if !turkish.IsVowel(next) && n.takes(stem, next) {
+			stem += string(next)
+		}
+	}
+	for _, suffix := range n.Suffixes {
+		if suffix.fits(stem, len(syllables)) {
+			return turkish.Title(stem + suffix.Suffix)
+		}
+	}
+	return ""
+}
+
+// takes reports whether the consonant next may be added to stem: always
+// after a vowel, and after a consonant only when the two are a cluster.
+func (n Nickname) takes(stem string, next rune) bool {
+	last, _ := utf8.DecodeLastRuneInString(stem)
+	return turkish.IsVowel(last) || slices.Contains(n.Clusters, string([]rune{last, next}))
+}
@@ -0,0 +1,35 @@
+package karabasan
+
+import "testing"
+
+func TestNickname(t *testing.T) {
+	content, err := LoadContent("../data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nickname := content.Stages.Stage5.Nickname
+	for name, want := range map[string]string{
+		"Mehmet":     "Memoş",
+		"MEHMET ALİ": "Memoş",
+		"Ayşe":       "Ayşoş",
+		"Mustafa":    "Musti",
+		"Fatma":      "Fatoş",
+		"İbrahim":    "İbiş",
+		"ibrahim":    "İbiş",
+		"Gülay":      "Gülüş",
+		"Emine":      "Emoş",
+		"Ali":        "Aloş",
+		"Can":        "Canço",
+		"Gül":        "Gülke",
+		"Al":         "Alço",
+		"Ahmet":      "Ahmo",
+		"Hüseyin":    "Hüso",
+		"Xyz":        "",
+		"42":         "",
+		"":           "",
+	} {
+		if got := nickname.of(name); got != want {
+			t.Errorf("nickname of %q = %q, want %q", name, got, want)
+		}
+	}
+}
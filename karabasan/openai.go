@@ -0,0 +1,98 @@
+package karabasan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultModelURL is where Ollama serves its OpenAI-compatible API.
+const DefaultModelURL = "http://localhost:11434/v1"
+
+// OpenAIResponder is a Responder backed by the chat completions endpoint of
+// an OpenAI-compatible server, such as a local Ollama or llama.cpp server.
+type OpenAIResponder struct {
+	// URL is the API's base URL; requests go to URL + "/chat/completions".
+	URL string
+	// Model is the model name the server knows, such as "llama3.2".
+	Model string
+	// APIKey is sent as a bearer token when set; local servers need none.
+	APIKey string
+	// MaxTokens caps the length of a reply.
+	MaxTokens int
+	// Client sends the requests; http.DefaultClient when nil. The session's
+	// timeout comes through the request context.
+	Client *http.Client
+}
+
+// NewOpenAIResponder creates a responder asking model at the API at url.
+func NewOpenAIResponder(url, model string) *OpenAIResponder {
+	return &OpenAIResponder{URL: url, Model: model, MaxTokens: 120}
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatCompletionRequest struct {
+	Model     string        `json:"model"`
+	Messages  []chatMessage `json:"messages"`
+	MaxTokens int           `json:"max_tokens,omitempty"`
+	Stream    bool          `json:"stream"`
+}
+
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// maxCompletionSize bounds the response body read from the server.
+const maxCompletionSize = 1 << 20
+
+func (r *OpenAIResponder) Reply(ctx context.Context, req ResponderRequest) (string, error) {
+	messages := []chatMessage{{Role: "system", Content: req.System}}
+	if req.Question != "" {
+		messages = append(messages, chatMessage{Role: "assistant", Content: req.Question})
+	}
+	messages = append(messages, chatMessage{Role: "user", Content: req.Answer})
+	body, err := json.Marshal(chatCompletionRequest{Model: r.Model, Messages: messages, MaxTokens: r.MaxTokens})
+	if err != nil {
+		return "", err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(r.URL, "/")+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if r.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+r.APIKey)
+	}
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("model server: %s", resp.Status)
+	}
+	var completion chatCompletionResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxCompletionSize)).Decode(&completion); err != nil {
+		return "", fmt.Errorf("model server: decoding the reply: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", errors.New("model server: no reply")
+	}
+	return completion.Choices[0].Message.Content, nil
+}
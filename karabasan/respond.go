@@ -0,0 +1,167 @@
+package karabasan
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"strings"
+)
+
+// Options configure a session made by NewSession.
+type Options struct {
+	// Seed seeds the session's random choices; 0 picks a random seed.
+	Seed uint64
+	// LoginName is offered as the user's name in stage1.
+	LoginName string
+	// Difficulty is the stage8 preset; asked in the conversation when empty.
+	Difficulty string
+	// ScoresPath is the score table the round is saved to; empty means the
+	// round is not saved.
+	ScoresPath string
+	// Responder replies to free-text answers; canned lines are used when nil.
+	Responder Responder
+	// OnError is told of the errors the conversation carries on past, such
+	// as a score table that cannot be saved.
+	OnError func(error)
+}
+
+// Reply is what Karabasan says between two answers of the user.
+type Reply struct {
+	// Messages are Karabasan's lines, in order, without colours.
+	Messages []string
+	// Prompt is the question waiting for the user's answer; it can be empty
+	// when Karabasan just waits.
+	Prompt string
+	// Expect is the kind of answer Prompt expects; empty once Done.
+	Expect InputKind
+	// Stage is the stage the conversation is in.
+	Stage StageID
+	// Done is set when the conversation is over; Respond must not be
+	// called again.
+	Done bool
+	// Events are all the events behind the reply, including the thinking
+	// and pauses, for clients that want to play the timing.
+	Events []Event
+}
+
+var (
+	ErrStarted    = errors.New("karabasan: session already started")
+	ErrNotStarted = errors.New("karabasan: session not started")
+	ErrDone       = errors.New("karabasan: conversation is over")
+	ErrTerminal   = errors.New("karabasan: session has terminal streams; use Run")
+)
+
+// NewSession creates a session driven by Start and Respond instead of by
+// terminal streams: nothing is printed and nothing waits. A session that
+// is abandoned before it is Done must be closed.
+func NewSession(content *Content, opts Options) *Session {
+	answer := new(answerReader)
+	s := NewTerminalSession(content, answer, io.Discard)
+	s.answer = answer
+	s.Plain = true
+	s.LoginName = opts.LoginName
+	s.Difficulty = opts.Difficulty
+	s.ScoresPath = opts.ScoresPath
+	s.Responder = opts.Responder
+	s.OnError = opts.OnError
+	if opts.Seed != 0 {
+		s.SetSeed(opts.Seed)
+	}
+	return s
+}
+
+// Start begins the conversation and returns everything Karabasan says up
+// to the first question.
+func (s *Session) Start() (Reply, error) {
+	if s.answer == nil {
+		return Reply{}, ErrTerminal
+	}
+	if s.next != nil || s.done {
+		return Reply{}, ErrStarted
+	}
+	s.next, s.stop = iter.Pull(s.events)
+	return s.reply()
+}
+
+// Respond gives Karabasan the user's answer to the last prompt and returns
+// everything it says up to the next one.
+func (s *Session) Respond(text string) (Reply, error) {
+	switch {
+	case s.next == nil:
+		return Reply{}, ErrNotStarted
+	case s.done:
+		return Reply{}, ErrDone
+	}
+	s.answer.set(text)
+	return s.reply()
+}
+
+// Close ends a conversation started with Start, even in the middle.
+func (s *Session) Close() {
+	if s.stop != nil {
+		s.stop()
+	}
+	s.done = true
+}
+
+// events runs the conversation as a sequence of events. The stages block
+// in readLine after every prompt; the sequence stops there until Respond
+// asks for the next event.
+func (s *Session) events(yield func(Event) bool) {
+	stopped := false
+	s.Events = func(e Event) {
+		if stopped {
+			return
+		}
+		if !yield(e) {
+			// Closed: unwind the stages the way a hung-up user would.
+			stopped = true
+			panic(ErrInputClosed)
+		}
+	}
+	if err := s.Run(FirstStage); err != nil && err != ErrInputClosed {
+		s.err = err
+	}
+}
+
+// reply collects the events up to the next prompt or the end.
+func (s *Session) reply() (Reply, error) {
+	var r Reply
+	for {
+		e, ok := s.next()
+		if !ok {
+			s.Close()
+			r.Done = true
+			break
+		}
+		r.Events = append(r.Events, e)
+		if e.Type == EventSay {
+			r.Messages = append(r.Messages, e.Text)
+		}
+		if e.Type == EventPrompt {
+			r.Prompt, r.Expect = e.Text, e.Expect
+			break
+		}
+	}
+	r.Stage = s.Stage
+	return r, s.err
+}
+
+// answerReader hands the stages the user's answers one at a time; it is
+// empty, and so at its end, whenever the stages read without an answer.
+type answerReader struct {
+	pending string
+}
+
+func (r *answerReader) set(text string) {
+	r.pending = strings.NewReplacer("\r", " ", "\n", " ").Replace(text) + "\n"
+}
+
+func (r *answerReader) Read(p []byte) (int, error) {
+	if r.pending == "" {
+		return 0, io.EOF
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,143 @@
+package karabasan
+
+import (
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newTestSession(t *testing.T, opts Options) *Session {
+	t.Helper()
+	content, err := LoadContent("../data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSession(content, opts)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestStartAndRespond(t *testing.T) {
+	s := newTestSession(t, Options{Seed: 1})
+	reply, err := s.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Merhaba, hoş geldin.", "Ben yeni nesil bir terminal arayüzüyüm."}; !slices.Equal(reply.Messages, want) {
+		t.Errorf("Start said %q, want %q", reply.Messages, want)
+	}
+	if reply.Prompt != "senin adın ne güzelim?" || reply.Expect != InputText || reply.Stage != stage1ID || reply.Done {
+		t.Errorf("Start = %+v, want the name question", reply)
+	}
+
+	reply, err = s.Respond("Ali")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.Messages) != 1 || !strings.Contains(reply.Messages[0], "Ali") {
+		t.Errorf("Respond said %q, want the greeting by name", reply.Messages)
+	}
+	if reply.Expect != InputNumber || reply.Stage != stage2ID {
+		t.Errorf("Respond = %+v, want the age question", reply)
+	}
+	if s.UserName != "Ali" {
+		t.Errorf("UserName = %q, want Ali", s.UserName)
+	}
+
+	reply, err = s.Respond("yaşım yok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.Messages) != 1 || reply.Messages[0] != s.content.Stages.Stage2.InvalidInput || reply.Expect != InputNumber {
+		t.Errorf("a bad age gave %+v, want the invalid input line and the same question", reply)
+	}
+}
+
+func TestRespondMatchesTerminalSession(t *testing.T) {
+	// The same seed and answers give the same conversation either way.
+	answers := []string{"Ali", "30", "175", "70"}
+	s := newTestSession(t, Options{Seed: 7})
+	var said []string
+	reply, _ := s.Start()
+	said = append(said, reply.Messages...)
+	for _, answer := range answers {
+		reply, _ = s.Respond(answer)
+		said = append(said, reply.Messages...)
+	}
+
+	var out strings.Builder
+	terminal := NewTerminalSession(s.content, strings.NewReader(strings.Join(answers, "\n")+"\n"), &out)
+	terminal.Plain = true
+	terminal.SetSeed(7)
+	terminal.Run(FirstStage)
+	for _, line := range said {
+		for _, part := range strings.Split(line, "\n") {
+			if !strings.Contains(out.String(), part) {
+				t.Errorf("the terminal session did not say %q:\n%s", part, out.String())
+			}
+		}
+	}
+}
+
+func TestSessionLifecycleErrors(t *testing.T) {
+	s := newTestSession(t, Options{})
+	if _, err := s.Respond("Ali"); err != ErrNotStarted {
+		t.Errorf("Respond before Start = %v, want %v", err, ErrNotStarted)
+	}
+	s.Start()
+	if _, err := s.Start(); err != ErrStarted {
+		t.Errorf("second Start = %v, want %v", err, ErrStarted)
+	}
+	s.Close()
+	if _, err := s.Respond("Ali"); err != ErrDone {
+		t.Errorf("Respond after Close = %v, want %v", err, ErrDone)
+	}
+
+	terminal := NewTerminalSession(s.content, strings.NewReader(""), io.Discard)
+	if _, err := terminal.Start(); err != ErrTerminal {
+		t.Errorf("Start on a terminal session = %v, want %v", err, ErrTerminal)
+	}
+}
+
+func TestRespondToTheEnd(t *testing.T) {
+	s := newTestSession(t, Options{Seed: 5, Difficulty: "kolay"})
+	letters := []rune("aeiıoöuübcçdfgğhjklmnprsştvyz")
+	guess, cell, letter := 0, 0, 0
+	reply, err := s.Start()
+	for turn := 0; !reply.Done; turn++ {
+		if err != nil || turn > 500 {
+			t.Fatalf("turn %d: %+v, %v", turn, reply, err)
+		}
+		answer := "Ali"
+		switch {
+		case reply.Expect == InputYesNo:
+			answer = "h"
+		case reply.Stage == stage8ID:
+			guess++
+			answer = strconv.Itoa(guess)
+		case reply.Stage == xoxID:
+			answer = strconv.Itoa(cell%9 + 1)
+			cell++
+		case reply.Expect == InputNumber:
+			answer = "70"
+		case reply.Stage == hangmanID:
+			answer = string(letters[letter%len(letters)])
+			letter++
+		case reply.Stage == stage9ID:
+			answer = "b"
+		case reply.Stage == rockPaperScissorsID:
+			answer = "taş"
+		case reply.Stage == chatID:
+			answer = "görüşürüz"
+		}
+		reply, err = s.Respond(answer)
+	}
+	if err != nil || reply.Expect != "" || reply.Stage != replayID {
+		t.Errorf("last reply = %+v, %v; want the end of the conversation", reply, err)
+	}
+	if _, err := s.Respond("e"); err != ErrDone {
+		t.Errorf("Respond after the end = %v, want %v", err, ErrDone)
+	}
+}
@@ -0,0 +1,124 @@
+package karabasan
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Responder writes a free-text reply to something the user said, for the
+// places where Karabasan would otherwise shrug it off with a canned line.
+// Reply must give up when ctx is done; the stage then falls back to the
+// line in data.json.
+type Responder interface {
+	Reply(ctx context.Context, req ResponderRequest) (string, error)
+}
+
+// ResponderRequest is what a Responder is asked.
+type ResponderRequest struct {
+	// System is the persona prompt, built from data.json and what the user
+	// told so far.
+	System string
+	// Question is what Karabasan asked; empty in the free chat.
+	Question string
+	// Answer is what the user said.
+	Answer string
+}
+
+// Persona is the content the Responder's system prompt is built from:
+// Prompt describes Karabasan, Examples introduces a few of his own lines,
+// and Name, Age and Hometown state the user's answers.
+type Persona struct {
+	Prompt   string `json:"prompt"`
+	Examples string `json:"examples"`
+	Name     string `json:"name"`
+	Age      string `json:"age"`
+	Hometown string `json:"hometown"`
+}
+
+// DefaultResponderTimeout bounds every Responder call of a session whose
+// ResponderTimeout is not set.
+const DefaultResponderTimeout = 3 * time.Second
+
+// maxResponderFailures is how many failed calls in a row make a session
+// stop asking its Responder, so that a dead model does not hold up every
+// question.
+const maxResponderFailures = 2
+
+// maxReplyLines cuts long-winded models short.
+const maxReplyLines = 3
+
+// personaPrompt builds the system prompt for the session's Responder.
+func (s *Session) personaPrompt() string {
+	persona := s.content.Persona
+	var b strings.Builder
+	b.WriteString(s.render(persona.Prompt))
+	if persona.Examples != "" {
+		b.WriteString("\n\n" + persona.Examples)
+		for _, line := range append(append([]string(nil), s.content.Laughs...), s.content.Proverbs...) {
+			b.WriteString("\n- " + strings.ReplaceAll(line, "\n", " "))
+		}
+	}
+	b.WriteString("\n")
+	if s.UserName != "" && persona.Name != "" {
+		b.WriteString("\n" + s.render(persona.Name, s.UserName))
+	}
+	if s.Age > 0 && persona.Age != "" {
+		b.WriteString("\n" + s.render(persona.Age, s.Age))
+	}
+	if s.Hometown != "" && persona.Hometown != "" {
+		b.WriteString("\n" + s.render(persona.Hometown, s.Hometown))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// freeReply asks the session's Responder for a reply to answer, given to
+// question. ok is false when there is no Responder, it failed or it ran out
+// of time, and the caller says its canned line instead.
+func (s *Session) freeReply(question, answer string) (reply string, ok bool) {
+	if s.Responder == nil || s.failedReplies >= maxResponderFailures || strings.TrimSpace(answer) == "" {
+		return "", false
+	}
+	timeout := s.ResponderTimeout
+	if timeout <= 0 {
+		timeout = DefaultResponderTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	reply, err := s.Responder.Reply(ctx, ResponderRequest{
+		System:   s.personaPrompt(),
+		Question: strings.TrimSpace(stripColors(question)),
+		Answer:   answer,
+	})
+	if reply = cleanReply(reply); err != nil || reply == "" {
+		s.failedReplies++
+		return "", false
+	}
+	s.failedReplies = 0
+	return reply, true
+}
+
+// cleanReply trims a model's reply to a few lines without colour codes.
+func cleanReply(reply string) string {
+	var lines []string
+	for _, line := range strings.Split(stripColors(reply), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > maxReplyLines {
+		lines = lines[:maxReplyLines]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// answerFreely reads a free-text answer to question and replies to it
+// through the Responder, or with the canned content line when that has
+// nothing to say.
+func (s *Session) answerFreely(question, canned string) {
+	if reply, ok := s.freeReply(question, s.readLine()); ok {
+		s.aiResponse(reply)
+		return
+	}
+	s.aiResponse(s.render(canned))
+}
@@ -0,0 +1,185 @@
+package karabasan
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockModel is an OpenAI-compatible chat completions server that answers
+// every request with reply, after delay.
+type mockModel struct {
+	reply  string
+	status int
+	delay  time.Duration
+
+	mu       sync.Mutex
+	requests []chatCompletionRequest
+	auth     []string
+}
+
+func (m *mockModel) start(t *testing.T) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		var req chatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		m.mu.Lock()
+		m.requests = append(m.requests, req)
+		m.auth = append(m.auth, r.Header.Get("Authorization"))
+		m.mu.Unlock()
+		select {
+		case <-time.After(m.delay):
+		case <-r.Context().Done():
+			return
+		}
+		if m.status != 0 {
+			w.WriteHeader(m.status)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []any{map[string]any{"message": chatMessage{Role: "assistant", Content: m.reply}}},
+		})
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// asked returns the requests the server got so far.
+func (m *mockModel) asked() []chatCompletionRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]chatCompletionRequest(nil), m.requests...)
+}
+
+func TestOpenAIResponder(t *testing.T) {
+	model := &mockModel{reply: "  dedenden mi kaldı lan o isim? hehe!\n"}
+	ts := model.start(t)
+	r := NewOpenAIResponder(ts.URL+"/v1/", "karabasan-test")
+	r.APIKey = "gizli"
+	reply, err := r.Reply(context.Background(), ResponderRequest{System: "sen Karabasan'sın", Question: "adı nerden geliyo?", Answer: "dedemden"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != model.reply {
+		t.Errorf("Reply = %q, want %q", reply, model.reply)
+	}
+	req := model.asked()[0]
+	want := []chatMessage{
+		{Role: "system", Content: "sen Karabasan'sın"},
+		{Role: "assistant", Content: "adı nerden geliyo?"},
+		{Role: "user", Content: "dedemden"},
+	}
+	if req.Model != "karabasan-test" || req.Stream || len(req.Messages) != len(want) {
+		t.Fatalf("request = %+v", req)
+	}
+	for i := range want {
+		if req.Messages[i] != want[i] {
+			t.Errorf("message %d = %+v, want %+v", i, req.Messages[i], want[i])
+		}
+	}
+	if model.auth[0] != "Bearer gizli" {
+		t.Errorf("Authorization = %q", model.auth[0])
+	}
+
+	model.status = http.StatusInternalServerError
+	if _, err := r.Reply(context.Background(), ResponderRequest{Answer: "e"}); err == nil {
+		t.Error("a failing server gave no error")
+	}
+}
+
+// runStage5 plays stage5 with seed 183, which asks every question, giving
+// answer to all of them.
+func runStage5(t *testing.T, responder Responder, timeout time.Duration) string {
+	t.Helper()
+	content, err := LoadContent("../data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	s := NewTerminalSession(content, strings.NewReader(strings.Repeat("dedemden\n", 12)), &out)
+	s.Plain = true
+	s.SetSeed(183)
+	s.UserName, s.Age, s.Hometown = "Mehmet", 30, "Isparta"
+	s.Responder = responder
+	s.ResponderTimeout = timeout
+	s.Run(stage5ID)
+	return out.String()
+}
+
+func TestStage5AsksTheResponder(t *testing.T) {
+	model := &mockModel{reply: "dedenden mi kaldı lan o isim? hehe!"}
+	ts := model.start(t)
+	out := runStage5(t, NewOpenAIResponder(ts.URL+"/v1", "test"), time.Second)
+	if !strings.Contains(out, model.reply) {
+		t.Errorf("the model's reply was not said:\n%s", out)
+	}
+	if canned := "üüüü! baya uzaktan geliyomuş!"; strings.Contains(out, canned) {
+		t.Errorf("the canned reply %q was said too:\n%s", canned, out)
+	}
+
+	req := model.asked()[0]
+	system := req.Messages[0].Content
+	for _, want := range []string{"Sen Karabasan'sın", "he he he he...", "Karşındakinin adı Mehmet.", "30 yaşında.", "Memleketi Isparta."} {
+		if !strings.Contains(system, want) {
+			t.Errorf("the persona prompt lacks %q:\n%s", want, system)
+		}
+	}
+	if got := req.Messages[1].Content; !strings.Contains(got, "adı nerden geliyo?") {
+		t.Errorf("asked about %q, want the name origin question", got)
+	}
+	if got := req.Messages[2].Content; got != "dedemden" {
+		t.Errorf("the user's answer was %q", got)
+	}
+}
+
+func TestStage5FallsBackToCannedLines(t *testing.T) {
+	for name, model := range map[string]*mockModel{
+		"slow":   {reply: "geç kaldım", delay: time.Second},
+		"broken": {status: http.StatusServiceUnavailable},
+		"silent": {reply: "  \n "},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ts := model.start(t)
+			start := time.Now()
+			out := runStage5(t, NewOpenAIResponder(ts.URL+"/v1", "test"), 50*time.Millisecond)
+			if !strings.Contains(out, "üüüü! baya uzaktan geliyomuş!") {
+				t.Errorf("the canned reply was not said:\n%s", out)
+			}
+			if strings.Contains(out, "geç kaldım") {
+				t.Errorf("a reply that came too late was said:\n%s", out)
+			}
+			// After two failures in a row the model is left alone.
+			if n := len(model.asked()); n > maxResponderFailures {
+				t.Errorf("the model was asked %d times", n)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("stage5 took %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestCleanReply(t *testing.T) {
+	for in, want := range map[string]string{
+		"  hehe  ":                        "hehe",
+		"bir\n\n  iki \nüç\ndört\nbeş":    "bir\niki\nüç",
+		ColorCyan + "renkli" + ColorReset: "renkli",
+		"\n \n":                           "",
+	} {
+		if got := cleanReply(in); got != want {
+			t.Errorf("cleanReply(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
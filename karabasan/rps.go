@@ -0,0 +1,183 @@
+package karabasan
+
+import (
+	"fmt"
+	"strings"
+
+	"k.go/karabasan/turkish"
+)
+
+// RockPaperScissors is the content of the taş-kağıt-makas stage.
+type RockPaperScissors struct {
+	Rounds       int      `json:"rounds"`
+	Intro        string   `json:"intro"`
+	MovePrompt   string   `json:"movePrompt"`
+	InvalidMove  string   `json:"invalidMove"`
+	Moves        []string `json:"moves"`
+	Reveal       string   `json:"reveal"`
+	RoundWin     []string `json:"roundWin"`
+	RoundLose    []string `json:"roundLose"`
+	RoundDraw    []string `json:"roundDraw"`
+	Tally        string   `json:"tally"`
+	StreakLength int      `json:"streakLength"`
+	Streaks      struct {
+		Win  []string `json:"win"`
+		Lose []string `json:"lose"`
+		Draw []string `json:"draw"`
+	} `json:"streaks"`
+	CheatingMinRounds int      `json:"cheatingMinRounds"`
+	CheatingRatio     float64  `json:"cheatingRatio"`
+	Cheating          []string `json:"cheating"`
+	Responses         struct {
+		Win      string `json:"win"`
+		Cheating string `json:"cheating"`
+	} `json:"responses"`
+}
+
+// check reports content the stage cannot be played with: a name is
+// needed for each of taş, kağıt and makas.
+func (rps RockPaperScissors) check() error {
+	if len(rps.Moves) < 3 {
+		return fmt.Errorf("rockPaperScissors.moves: %d moves, need 3", len(rps.Moves))
+	}
+	return nil
+}
+
+// Moves are indexed so that (a-b+3)%3 == 1 means a beats b.
+const (
+	moveRock = iota
+	movePaper
+	moveScissors
+)
+
+// Outcomes of a single throw, from the user's point of view.
+const (
+	rpsDraw = iota
+	rpsWin
+	rpsLose
+)
+
+// parseMove reads t/k/m or a full move name, returning -1 for anything else.
+func parseMove(input string) int {
+	input = strings.TrimSpace(turkish.Lower(input))
+	switch {
+	case input == "":
+		return -1
+	case strings.HasPrefix(input, "t"):
+		return moveRock
+	case strings.HasPrefix(input, "k"):
+		return movePaper
+	case strings.HasPrefix(input, "m"):
+		return moveScissors
+	}
+	return -1
+}
+
+// rpsResult tells whether the user's move beat Karabasan's.
+func rpsResult(user, bot int) int {
+	switch (user - bot + 3) % 3 {
+	case 1:
+		return rpsWin
+	case 2:
+		return rpsLose
+	}
+	return rpsDraw
+}
+
+// predictMove guesses the user's next move from how often they played each
+// one so far, and returns the move that beats it. Ties are broken randomly.
+func (s *Session) predictMove(history []int) int {
+	if len(history) == 0 {
+		return s.randomInt(3)
+	}
+	var counts [3]int
+	for _, m := range history {
+		counts[m]++
+	}
+	var likely []int
+	best := -1
+	for m, c := range counts {
+		if c > best {
+			best = c
+			likely = likely[:0]
+		}
+		if c == best {
+			likely = append(likely, m)
+		}
+	}
+	predicted := likely[s.randomInt(len(likely))]
+	return (predicted + 1) % 3
+}
+
+// stageRockPaperScissors is a best-of-N taş-kağıt-makas against a predictor.
+func (s *Session) stageRockPaperScissors() StageID {
+	rps := s.content.Stages.RockPaperScissors
+	needed := rps.Rounds/2 + 1
+	wins, losses, played := 0, 0, 0
+	streak, lastOutcome := 0, -1
+	accused := false
+	var history []int
+
+	s.aiResponse(s.render(rps.Intro, s.UserName, rps.Rounds))
+	for wins < needed && losses < needed {
+		s.userPrompt(s.render(rps.MovePrompt))
+		input := s.readLine()
+		move := parseMove(input)
+		if move < 0 {
+			s.aiResponse(s.render(rps.InvalidMove))
+			continue
+		}
+		bot := s.predictMove(history)
+		history = append(history, move)
+		played++
+
+		s.aiResponse(s.render(rps.Reveal, rps.Moves[bot], rps.Moves[move]))
+		outcome := rpsResult(move, bot)
+		switch outcome {
+		case rpsWin:
+			wins++
+			s.aiResponse(s.render(s.randomLine(rps.RoundWin)))
+		case rpsLose:
+			losses++
+			s.aiResponse(s.render(s.randomLine(rps.RoundLose)))
+		default:
+			s.aiResponse(s.render(s.randomLine(rps.RoundDraw)))
+		}
+
+		if outcome == lastOutcome {
+			streak++
+		} else {
+			streak, lastOutcome = 1, outcome
+		}
+		if rps.StreakLength > 0 && streak >= rps.StreakLength {
+			switch outcome {
+			case rpsWin:
+				s.aiResponse(s.render(s.randomLine(rps.Streaks.Win)))
+			case rpsLose:
+				s.aiResponse(s.render(s.randomLine(rps.Streaks.Lose)))
+				s.laugh()
+			default:
+				s.aiResponse(s.render(s.randomLine(rps.Streaks.Draw)))
+			}
+		}
+
+		if !accused && played >= rps.CheatingMinRounds && float64(wins)/float64(played) >= rps.CheatingRatio {
+			s.aiResponse(s.render(s.randomLine(rps.Cheating)))
+			s.swear()
+			accused = true
+		}
+		s.aiResponse(s.render(rps.Tally, wins, losses))
+	}
+
+	if losses > wins {
+		s.aiResponse(s.render(rps.Responses.Win, losses, wins))
+	} else {
+		s.aiResponse(s.render(rps.Responses.Cheating))
+		accused = true
+	}
+
+	s.Round.RPSWins = wins
+	s.Round.RPSLosses = losses
+	s.Round.RPSAccused = accused
+	return xoxID
+}
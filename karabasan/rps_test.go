@@ -0,0 +1,72 @@
+package karabasan
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRPSResult(t *testing.T) {
+	for _, tt := range []struct {
+		user, bot, want int
+	}{
+		{moveRock, moveRock, rpsDraw},
+		{moveRock, movePaper, rpsLose},
+		{moveRock, moveScissors, rpsWin},
+		{movePaper, moveRock, rpsWin},
+		{movePaper, movePaper, rpsDraw},
+		{movePaper, moveScissors, rpsLose},
+		{moveScissors, moveRock, rpsLose},
+		{moveScissors, movePaper, rpsWin},
+		{moveScissors, moveScissors, rpsDraw},
+	} {
+		if got := rpsResult(tt.user, tt.bot); got != tt.want {
+			t.Errorf("rpsResult(%d, %d) = %d, want %d", tt.user, tt.bot, got, tt.want)
+		}
+	}
+}
+
+func TestRPSCountersTheFavouriteMove(t *testing.T) {
+	s := NewTerminalSession(nil, strings.NewReader(""), io.Discard)
+	for _, tt := range []struct {
+		history   []int
+		favourite int
+	}{
+		{[]int{moveRock}, moveRock},
+		{[]int{movePaper, movePaper, moveRock}, movePaper},
+		{[]int{moveScissors, moveRock, moveScissors, movePaper}, moveScissors},
+		{[]int{moveRock, moveScissors, moveRock, moveScissors, moveRock}, moveRock},
+	} {
+		for range 10 {
+			if bot := s.predictMove(tt.history); rpsResult(tt.favourite, bot) != rpsLose {
+				t.Errorf("predictMove(%v) = %d, which does not beat %d", tt.history, bot, tt.favourite)
+				break
+			}
+		}
+	}
+}
+
+func TestRPSContentChecked(t *testing.T) {
+	if err := (RockPaperScissors{Moves: []string{"taş", "kağıt"}}).check(); err == nil || !strings.Contains(err.Error(), "rockPaperScissors.moves") {
+		t.Errorf("two moves: check() = %v", err)
+	}
+	if err := (RockPaperScissors{Moves: []string{"taş", "kağıt", "makas"}}).check(); err != nil {
+		t.Errorf("three moves: check() = %v", err)
+	}
+
+	// LoadContent refuses such a file instead of panicking in the stage.
+	data, err := os.ReadFile("../data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "data.json")
+	data = []byte(strings.Replace(string(data), `"moves": ["taş", "kağıt", "makas"]`, `"moves": ["taş"]`, 1))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadContent(path); err == nil || !strings.Contains(err.Error(), "rockPaperScissors.moves") {
+		t.Errorf("LoadContent with one move = %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package karabasan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"k.go/karabasan/turkish"
+)
+
+// ScoreEntry is one finished round of the game stages.
+type ScoreEntry struct {
+	UserName      string    `json:"userName"`
+	Difficulty    string    `json:"difficulty"`
+	Stage8Solved  bool      `json:"stage8Solved"`
+	Stage8Count   int       `json:"stage8GuessCount"`
+	HangmanSolved bool      `json:"hangmanSolved"`
+	HangmanWrong  int       `json:"hangmanWrong"`
+	Stage9Count   int       `json:"stage9GuessCount"`
+	Stage9Lied    bool      `json:"stage9Lied"`    // the user gave contradictory y/d answers
+	Stage9Accuse  bool      `json:"stage9Accused"` // Karabasan lost and cried "hile"
+	RPSWins       int       `json:"rpsWins"`
+	RPSLosses     int       `json:"rpsLosses"`
+	RPSAccused    bool      `json:"rpsAccused"`
+	XOXResult     string    `json:"xoxResult"` // win, lose or draw for the user
+	Time          time.Time `json:"time"`
+}
+
+// ScoreTable is the on-disk layout of scores.json.
+type ScoreTable struct {
+	Version int          `json:"version"`
+	Entries []ScoreEntry `json:"entries"`
+}
+
+const scoreTableVersion = 1
+
+// DefaultScoresPath returns $XDG_DATA_HOME/karabasan/scores.json, falling back to ~/.local/share.
+func DefaultScoresPath() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "karabasan", "scores.json"), nil
+}
+
+// ReadScores loads the table at path. A missing file is an empty table.
+func ReadScores(path string) (ScoreTable, error) {
+	table := ScoreTable{Version: scoreTableVersion}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return table, nil
+	}
+	if err != nil {
+		return table, err
+	}
+	if err := json.Unmarshal(data, &table); err != nil {
+		return table, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return table, nil
+}
+
+// writeScores replaces the table at path atomically via a temp file and rename.
+func writeScores(path string, table ScoreTable) error {
+	data, err := json.MarshalIndent(table, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".scores-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// withScores runs fn on the score table at path while holding the lock file,
+// so concurrent sessions do not lose each other's entries. The table is
+// written back only when fn reports a change.
+func withScores(path string, fn func(table *ScoreTable) bool) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	if err := lockFile(lock); err != nil {
+		return err
+	}
+	defer unlockFile(lock)
+
+	table, err := ReadScores(path)
+	if err != nil {
+		return err
+	}
+	if !fn(&table) {
+		return nil
+	}
+	table.Version = scoreTableVersion
+	return writeScores(path, table)
+}
+
+// BetterScore orders entries for the leaderboard: solved rounds first, then
+// fewer stage8 guesses, then a Karabasan that needed more guesses in stage9.
+func BetterScore(a, b ScoreEntry) bool {
+	if a.Stage8Solved != b.Stage8Solved {
+		return a.Stage8Solved
+	}
+	if a.Stage8Count != b.Stage8Count {
+		return a.Stage8Count < b.Stage8Count
+	}
+	if a.Stage9Count != b.Stage9Count {
+		return a.Stage9Count > b.Stage9Count
+	}
+	return a.Time.Before(b.Time)
+}
+
+// personalBest returns the best earlier entry of name on the given difficulty.
+func personalBest(table ScoreTable, name, difficulty string) (ScoreEntry, bool) {
+	var best ScoreEntry
+	found := false
+	for _, e := range table.Entries {
+		if !turkish.EqualFold(e.UserName, name) || !turkish.EqualFold(e.Difficulty, difficulty) {
+			continue
+		}
+		if !found || BetterScore(e, best) {
+			best = e
+			found = true
+		}
+	}
+	return best, found
+}
+
+// recordScore stores the finished round and returns the user's previous best
+// on the same difficulty, if there was one.
+func recordScore(path string, entry ScoreEntry) (ScoreEntry, bool, error) {
+	var best ScoreEntry
+	var found bool
+	err := withScores(path, func(table *ScoreTable) bool {
+		best, found = personalBest(*table, entry.UserName, entry.Difficulty)
+		table.Entries = append(table.Entries, entry)
+		return true
+	})
+	return best, found, err
+}
+
+// compareWithBest mocks or praises the user relative to their previous best.
+func (s *Session) compareWithBest(entry, best ScoreEntry, found bool) {
+	responses := s.content.Scores
+	switch {
+	case !found:
+		s.aiResponse(s.render(responses.First, s.UserName))
+	case BetterScore(entry, best) && entry.Stage8Solved:
+		s.aiResponse(s.render(responses.Better, best.Stage8Count, entry.Stage8Count))
+	case entry.Stage8Solved == best.Stage8Solved && entry.Stage8Count == best.Stage8Count:
+		s.aiResponse(s.render(responses.Same, entry.Stage8Count))
+	default:
+		s.aiResponse(s.render(responses.Worse, best.Stage8Count))
+		s.laugh()
+	}
+}
+
+// saveRound records the game stages of this session and comments on it.
+func (s *Session) saveRound(entry ScoreEntry) {
+	if s.ScoresPath == "" {
+		return
+	}
+	entry.UserName = s.UserName
+	entry.Time = s.Clock.Now()
+	best, found, err := recordScore(s.ScoresPath, entry)
+	if err != nil {
+		if s.OnError != nil {
+			s.OnError(fmt.Errorf("saving scores: %w", err))
+		}
+		return
+	}
+	s.compareWithBest(entry, best, found)
+}
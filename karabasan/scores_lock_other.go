@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package karabasan
+
+import "os"
+
+// lockFile is a no-op where the platform has no file locking.
+func lockFile(f *os.File) error { return nil }
+
+// unlockFile is a no-op where the platform has no file locking.
+func unlockFile(f *os.File) error { return nil }
@@ -0,0 +1,24 @@
+package karabasan
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSaveRoundReportsErrors(t *testing.T) {
+	content, err := LoadContent("../data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewTerminalSession(content, strings.NewReader(""), new(strings.Builder))
+	s.Plain = true
+	s.UserName = "Ali"
+	// A directory is no score table.
+	s.ScoresPath = t.TempDir()
+	var errs []error
+	s.OnError = func(err error) { errs = append(errs, err) }
+	s.saveRound(ScoreEntry{Difficulty: "orta", Stage8Count: 5, Stage8Solved: true})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "saving scores") {
+		t.Errorf("OnError got %v, want one error saving scores", errs)
+	}
+}
@@ -0,0 +1,16 @@
+package karabasan
+
+import "strings"
+
+// ScriptAnswers strips the comment lines (starting with "#") from a script
+// of answers, leaving one answer per line as the user would have typed them.
+func ScriptAnswers(script string) string {
+	var answers []string
+	for _, line := range strings.Split(script, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		answers = append(answers, line)
+	}
+	return strings.Join(answers, "\n")
+}
@@ -0,0 +1,394 @@
+// Package karabasan is the Karabasan chat bot. A Session plays the
+// conversation either on terminal streams, with NewTerminalSession and Run,
+// or one answer at a time, with NewSession, Start and Respond.
+package karabasan
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"k.go/karabasan/turkish"
+)
+
+// ANSI escape codes for coloring and text formatting.
+const (
+	ColorCyan    = "\033[36m"
+	ColorGreen   = "\033[32m"
+	ColorMagenta = "\033[35m" // New color for AI responses
+	ColorReset   = "\033[0m"
+
+	// A new, clean prompt symbol for user input.
+	promptSymbol = "> "
+)
+
+// Session is one conversation with Karabasan: the user's answers, the game
+// results, and the streams and layout the conversation is rendered with.
+type Session struct {
+	// User answers collected by the stages.
+	UserName string
+	Age      int
+	Height   int
+	Weight   int
+	Hometown string
+	Score    int        // stage8 guess count, compared against in stage9; 0 when unsolved
+	Round    ScoreEntry // filled in by the game stages, saved in stage10
+
+	// LoginName is the name the user logged in with, such as an SSH user
+	// name. stage1 offers it instead of asking, or teases it when it is
+	// plainly not a name.
+	LoginName string
+
+	// Difficulty is the stage8 preset name; asked in the conversation when empty.
+	Difficulty string
+
+	// Stage is the id of the stage currently running.
+	Stage StageID
+
+	// Plain turns off colours and all typing and thinking delays, for
+	// scripted runs and transcripts that have to be byte-for-byte stable.
+	Plain bool
+
+	// ScoresPath is the score table the game stages are saved to; empty
+	// means the round is not saved.
+	ScoresPath string
+
+	// OnError, when set, is told of the errors the conversation carries on
+	// past, such as a score table that cannot be saved. The package itself
+	// writes nowhere but the session's output.
+	OnError func(error)
+
+	// Echo, when set, receives every line the user typed. Terminals echo
+	// input themselves, so this is only needed for transcripts.
+	Echo io.Writer
+
+	// Clock times the typing, thinking and pauses.
+	Clock Clock
+
+	// Seed is the seed of the session's random source. Replaying a session
+	// with the same seed and the same inputs gives the same conversation.
+	Seed uint64
+
+	// Responder, when set, writes replies to the answers the stages have
+	// no canned reply for; see responder.go. ResponderTimeout bounds each
+	// call, DefaultResponderTimeout when zero.
+	Responder        Responder
+	ResponderTimeout time.Duration
+
+	// Events, when set, receives the conversation as typed events instead
+	// of it being rendered to out; see protocol.go.
+	Events func(Event)
+
+	// Terminal layout.
+	TerminalWidth  int
+	SeparatorWidth int
+
+	content        *Content
+	in             *bufio.Reader
+	out            io.Writer
+	rng            *rand.Rand
+	resizes        chan [2]int
+	previousJokeID int
+	errorCount     int
+	inputs         []string // every line read, for crash reports
+	prompt         string   // the last prompt, reported with the next read
+	chatMemory     []string // replies kept by the free chat for a later turn
+	failedReplies  int      // failed Responder calls in a row
+	askedLevel     bool     // Difficulty was picked in the conversation
+	skippedStage8  bool     // the round was restarted past stage8
+
+	// Set for sessions driven by Start and Respond.
+	answer *answerReader
+	next   func() (Event, bool)
+	stop   func()
+	done   bool
+	err    error
+}
+
+// NewTerminalSession creates a session reading answers from in and writing the
+// conversation to out, laid out for an 80 column terminal.
+func NewTerminalSession(content *Content, in io.Reader, out io.Writer) *Session {
+	s := &Session{
+		TerminalWidth:  80,
+		SeparatorWidth: 80,
+		Clock:          RealClock{},
+		content:        content,
+		in:             bufio.NewReader(in),
+		out:            out,
+		previousJokeID: -1,
+		resizes:        make(chan [2]int, 1),
+	}
+	s.SetSeed(rand.Uint64())
+	return s
+}
+
+// PresetDifficulty returns Difficulty when it was given up front, such as
+// by a flag, and "" when the user picked it in the conversation, where a
+// replay of the inputs picks it again.
+func (s *Session) PresetDifficulty() string {
+	if s.askedLevel {
+		return ""
+	}
+	return s.Difficulty
+}
+
+// Content returns the content the session talks from.
+func (s *Session) Content() *Content {
+	return s.content
+}
+
+// Inputs returns every line the user has typed so far, for crash reports.
+func (s *Session) Inputs() []string {
+	return s.inputs
+}
+
+// SetSeed restarts the session's random source from seed.
+func (s *Session) SetSeed(seed uint64) {
+	s.Seed = seed
+	s.rng = rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+}
+
+// Resize tells the session about a new terminal size. It is safe to call
+// from other goroutines; the stage picks the size up at its next output.
+func (s *Session) Resize(cols, rows int) {
+	for {
+		select {
+		case s.resizes <- [2]int{cols, rows}:
+			return
+		default:
+			// Drop a size that was not picked up yet; only the latest matters.
+			select {
+			case <-s.resizes:
+			default:
+			}
+		}
+	}
+}
+
+// applyResize updates the layout from the latest Resize call, if any.
+func (s *Session) applyResize() {
+	select {
+	case size := <-s.resizes:
+		s.TerminalWidth = size[0]
+		s.SeparatorWidth = size[0]
+	default:
+	}
+}
+
+// readLine reads one line of free text.
+func (s *Session) readLine() string {
+	return s.read(InputText)
+}
+
+// readNumber reads one line that the stage parses as a number.
+func (s *Session) readNumber() string {
+	return s.read(InputNumber)
+}
+
+// readYesNo reads one line that the stage takes as an e/h answer.
+func (s *Session) readYesNo() string {
+	return s.read(InputYesNo)
+}
+
+// read reads one line of user input without the trailing newline; kind is
+// what the stage expects, for the event protocol. When the input has ended
+// it unwinds the session with ErrInputClosed.
+func (s *Session) read(kind InputKind) string {
+	s.emit(Event{Type: EventPrompt, Text: s.prompt, Expect: kind})
+	s.prompt = ""
+	input, err := s.in.ReadString('\n')
+	if err != nil && input == "" {
+		panic(ErrInputClosed)
+	}
+	s.inputs = append(s.inputs, strings.TrimRight(input, "\r\n"))
+	if s.Echo != nil {
+		fmt.Fprint(s.Echo, strings.TrimRight(input, "\r\n")+"\n")
+	}
+	return turkish.Normalize(strings.TrimSpace(input))
+}
+
+// paint wraps text in the given ANSI colour, unless the session is plain.
+func (s *Session) paint(color, text string) string {
+	if s.Plain {
+		return text
+	}
+	return color + text + ColorReset
+}
+
+// sleep pauses the conversation for effect; plain sessions do not wait.
+func (s *Session) sleep(d time.Duration) {
+	s.emit(Event{Type: EventPause, Millis: d.Milliseconds()})
+	if !s.Plain {
+		s.Clock.Sleep(d)
+	}
+}
+
+// typewriterPrint simulates a typing effect by printing characters one by one.
+func (s *Session) typewriterPrint(text string) {
+	if s.Plain {
+		fmt.Fprintln(s.out, text)
+		return
+	}
+	typingSpeed := 15 * time.Millisecond
+	for _, char := range text {
+		fmt.Fprintf(s.out, "%c", char)
+		s.Clock.Sleep(typingSpeed)
+	}
+	fmt.Fprintln(s.out)
+}
+
+// centerPrint calculates the necessary padding and prints text in the middle of the terminal.
+// This function is updated to correctly handle multi-line strings by centering each line individually.
+func (s *Session) centerPrint(text string) {
+	if s.Events != nil {
+		if text := stripColors(text); strings.TrimSpace(text) != "" {
+			s.emit(Event{Type: EventSay, Text: text})
+		}
+		return
+	}
+	s.applyResize()
+	lines := strings.Split(text, "\n")
+	for _, line := range lines {
+		// Remove color codes for accurate width calculation
+		cleanLine := stripColors(line)
+
+		padding := (s.TerminalWidth - len(cleanLine)) / 2
+		if padding < 0 {
+			padding = 0
+		}
+		fmt.Fprint(s.out, strings.Repeat(" ", padding))
+		s.typewriterPrint(line)
+	}
+}
+
+// padBlock pads every line of s to the same width so that centerPrint keeps
+// multi-line drawings such as the gallows aligned.
+func padBlock(s string) string {
+	lines := strings.Split(s, "\n")
+	width := 0
+	for _, line := range lines {
+		if n := utf8.RuneCountInString(line); n > width {
+			width = n
+		}
+	}
+	for i, line := range lines {
+		lines[i] = line + strings.Repeat(" ", width-utf8.RuneCountInString(line))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// aiResponse is a new function dedicated to AI conversational responses.
+func (s *Session) aiResponse(text string) {
+	if s.Events != nil {
+		s.emit(Event{Type: EventThinking, Millis: time.Second.Milliseconds()})
+		s.emit(Event{Type: EventSay, Text: strings.Trim(stripColors(text), "\n")})
+		return
+	}
+	fmt.Fprint(s.out, s.paint(ColorCyan, "..."))
+	s.blinkingCursor(1 * time.Second)
+	fmt.Fprintln(s.out)
+	s.centerPrint(s.paint(ColorMagenta, text))
+}
+
+// blinkingCursor simulates a blinking cursor to represent the program "thinking."
+func (s *Session) blinkingCursor(duration time.Duration) {
+	if s.Plain {
+		return
+	}
+	blinkingSpeed := 500 * time.Millisecond
+	endTime := s.Clock.Now().Add(duration)
+	for s.Clock.Now().Before(endTime) {
+		fmt.Fprint(s.out, "_")
+		s.Clock.Sleep(blinkingSpeed)
+		fmt.Fprint(s.out, "\b \b")
+		s.Clock.Sleep(blinkingSpeed)
+	}
+}
+
+// userPrompt prints a separator and a clean prompt for the user.
+func (s *Session) userPrompt(text string) {
+	if s.Events != nil {
+		s.prompt = strings.TrimSpace(text)
+		return
+	}
+	s.applyResize()
+	fmt.Fprintln(s.out, s.paint(ColorGreen, strings.Repeat("-", s.SeparatorWidth)))
+	fmt.Fprintln(s.out, s.paint(ColorGreen, text))
+	fmt.Fprint(s.out, s.paint(ColorGreen, promptSymbol))
+}
+
+// stripColors removes the ANSI colour codes from s.
+func stripColors(s string) string {
+	s = strings.ReplaceAll(s, ColorCyan, "")
+	s = strings.ReplaceAll(s, ColorGreen, "")
+	s = strings.ReplaceAll(s, ColorMagenta, "")
+	return strings.ReplaceAll(s, ColorReset, "")
+}
+
+// countCharacters counts the number of visible characters in a string, ignoring ANSI codes.
+func countCharacters(s string) int {
+	return len(strings.ReplaceAll(stripColors(s), " ", ""))
+}
+
+// randomInt returns a random integer up to the given maximum (exclusive).
+func (s *Session) randomInt(max int) int {
+	return s.rng.IntN(max)
+}
+
+// randomLine picks one entry of lines, or returns "" when there are none.
+func (s *Session) randomLine(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return lines[s.randomInt(len(lines))]
+}
+
+// sayJoke prints a random joke, never the same one twice in a row. Some of
+// them are made up from the content pack; see markov.go.
+func (s *Session) sayJoke() {
+	if joke, ok := s.madeUpJoke(); ok {
+		s.aiResponse(joke)
+		return
+	}
+	jokes := s.content.Jokes
+	var jokeIndex int
+	for {
+		jokeIndex = s.randomInt(len(jokes))
+		if jokeIndex != s.previousJokeID || len(jokes) == 1 {
+			s.previousJokeID = jokeIndex
+			break
+		}
+	}
+	s.aiResponse(s.render(jokes[jokeIndex]))
+}
+
+// laugh prints a random laughing phrase.
+func (s *Session) laugh() {
+	s.aiResponse(s.render(s.randomLine(s.content.Laughs)))
+}
+
+// actDumb has a 50% chance of printing a "dumb" joke.
+func (s *Session) actDumb() {
+	if s.randomInt(2) == 1 {
+		s.aiResponse("\ngeri zekalı taklidi yap bakiim...\nTamam tamam bukadar yeter!!!\n")
+		s.laugh()
+	}
+}
+
+// swear hurls a made-up insult now and then, see markov.go; otherwise it
+// gives every rude phrase a 50% chance of being printed.
+func (s *Session) swear() {
+	if line, ok := s.madeUpSwear(); ok {
+		s.aiResponse(line)
+		return
+	}
+	for _, line := range s.content.Swears {
+		if s.randomInt(2) == 1 {
+			s.aiResponse(s.render(line))
+		}
+	}
+}
@@ -0,0 +1,493 @@
+package karabasan
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"k.go/karabasan/turkish"
+)
+
+// defaultDifficulty is used when the user just presses enter at the difficulty question.
+const defaultDifficulty = "orta"
+
+// stage10 concludes the game with a final joke.
+func (s *Session) stage10() StageID {
+	if s.Round.Difficulty != "" {
+		s.saveRound(s.Round)
+	}
+	s.aiResponse(s.render(s.content.Stages.Stage10.JokeIntro))
+	s.sayJoke()
+	return chatID
+}
+
+// stage9 is the number guessing game where the computer guesses the user's
+// number, in the range of the difficulty picked for stage8.
+func (s *Session) stage9() StageID {
+	d := s.playedDifficulty()
+	// The number is somewhere in lo…hi, both ends included.
+	lo, hi := d.Min, d.Max
+	guess := lo + s.randomInt(hi-lo+1)
+	s.errorCount = 0
+	guessCount := 0
+	s.aiResponse(s.render(s.content.Stages.Stage9.Prompts[0], d.Min, d.Max))
+	s.aiResponse(s.render(s.content.Stages.Stage9.Prompts[1]))
+	s.aiResponse(s.render(s.content.Stages.Stage9.Prompts[2]))
+	for {
+		guessCount++
+		s.aiResponse(fmt.Sprintf(" %d  ??\n", guess))
+		s.userPrompt("? ")
+		input := turkish.Lower(s.readLine())
+		if input == "y" || input == "d" {
+			nextLo, nextHi := lo, hi
+			if input == "y" {
+				nextLo = guess + 1
+			} else {
+				nextHi = guess - 1
+			}
+			// No number is left in the range: the user is lying.
+			if nextLo > nextHi {
+				s.swear()
+				s.errorCount++
+				if s.errorCount > 5 {
+					break
+				}
+				continue
+			}
+			lo, hi = nextLo, nextHi
+			guess = lo + s.randomInt(hi-lo+1)
+		} else if input == "b" {
+			break
+		}
+	}
+
+	// Fixed: The final response is now handled in a single, cohesive block.
+	accused := false
+	if s.skippedStage8 {
+		s.aiResponse(s.render(s.content.Stages.Stage9.Responses.Win, guessCount))
+	} else if s.Score == 0 {
+		s.aiResponse(s.render(s.content.Stages.Stage9.Responses.Unsolved, guessCount))
+	} else if guessCount < s.Score {
+		s.aiResponse(s.render(s.content.Stages.Stage9.Responses.Win, guessCount))
+	} else if guessCount > s.Score {
+		s.aiResponse(s.render(s.content.Stages.Stage9.Responses.Cheating))
+		accused = true
+	} else {
+		s.aiResponse(s.render(s.content.Stages.Stage9.Responses.Equal))
+	}
+
+	s.Round.Stage9Count = guessCount
+	s.Round.Stage9Lied = s.errorCount > 0
+	s.Round.Stage9Accuse = accused
+
+	return rockPaperScissorsID
+}
+
+// chooseDifficulty returns the preset picked by flag, or asks the user for one.
+func (s *Session) chooseDifficulty() Difficulty {
+	if d, ok := s.content.FindDifficulty(s.Difficulty); ok {
+		return d
+	}
+	for {
+		s.userPrompt(s.render(s.content.Stages.Stage8.DifficultyPrompt))
+		input := s.readLine()
+		if input == "" {
+			input = defaultDifficulty
+		}
+		if d, ok := s.content.FindDifficulty(input); ok {
+			s.Difficulty = d.Name
+			s.askedLevel = true
+			return d
+		}
+		s.aiResponse(s.render(s.content.Stages.Stage8.UnknownDifficulty))
+	}
+}
+
+// playedDifficulty returns the preset stage8 was played with, or the
+// default one when stage9 is played on its own.
+func (s *Session) playedDifficulty() Difficulty {
+	if d, ok := s.content.FindDifficulty(s.Difficulty); ok {
+		return d
+	}
+	if d, ok := s.content.FindDifficulty(defaultDifficulty); ok {
+		return d
+	}
+	return Difficulty{Name: defaultDifficulty, Min: 1, Max: 100}
+}
+
+// successMessage picks the stage8 success text for the given number of guesses.
+func (s *Session) successMessage(d Difficulty, guessCount int) string {
+	success := s.content.Stages.Stage8.Success
+	messages := []string{success.VeryGood, success.Good, success.Average, success.Poor, success.VeryPoor}
+	for i, tier := range d.SuccessTiers {
+		if i < len(messages) && guessCount <= tier {
+			return messages[i]
+		}
+	}
+	return success.Terrible
+}
+
+// stage8 is the number guessing game where the user guesses the computer's number.
+func (s *Session) stage8() StageID {
+	d := s.chooseDifficulty()
+	s.skippedStage8 = false
+	s.Round.Difficulty = d.Name
+	target := s.randomInt(d.Max-d.Min+1) + d.Min
+	var guess int
+	guessCount := 0
+	s.aiResponse(s.render(s.content.Stages.Stage8.Intro, s.UserName, d.Min, d.Max))
+	if d.MaxAttempts > 0 {
+		s.aiResponse(s.render(s.content.Stages.Stage8.AttemptsInfo, d.MaxAttempts))
+	}
+	for {
+		if d.MaxAttempts > 0 && guessCount >= d.MaxAttempts {
+			s.aiResponse(s.render(s.content.Stages.Stage8.OutOfAttempts, guessCount, target))
+			s.laugh()
+			// A failed round has no score for stage9 to beat.
+			s.Score = 0
+			s.Round.Stage8Solved = false
+			s.Round.Stage8Count = guessCount
+			return hangmanID
+		}
+		s.userPrompt(s.render(s.content.Stages.Stage8.GuessPrompt))
+		input := s.readNumber()
+		var err error
+		guess, err = strconv.Atoi(input)
+		if err != nil {
+			s.aiResponse(s.render(s.content.Stages.Stage8.InvalidGuess))
+			continue
+		}
+		guessCount++
+		if guess == target {
+			s.aiResponse(s.render(s.successMessage(d, guessCount), guessCount))
+			s.Score = guessCount
+			s.Round.Stage8Solved = true
+			s.Round.Stage8Count = guessCount
+			return hangmanID
+		} else {
+			if guess < d.Min || guess > d.Max {
+				s.aiResponse(s.render(s.content.Stages.Stage8.OutOfBounds, d.Min, d.Max))
+			} else if guess < target {
+				if target-guess > d.FarThreshold {
+					s.aiResponse(s.render(s.content.Stages.Stage8.TooLowFar))
+				} else {
+					s.aiResponse(s.render(s.content.Stages.Stage8.TooLow))
+				}
+			} else { // guess > target
+				if guess-target > d.FarThreshold {
+					s.aiResponse(s.render(s.content.Stages.Stage8.TooHighFar))
+				} else {
+					s.aiResponse(s.render(s.content.Stages.Stage8.TooHigh))
+				}
+			}
+		}
+	}
+}
+
+// stage7 asks for the user's hometown and responds based on the last vowel.
+// The responses put the suffixes on through the content templates.
+func (s *Session) stage7() StageID {
+	stage7 := s.content.Stages.Stage7
+	s.userPrompt(s.render(stage7.HometownPrompt, s.UserName))
+	s.Hometown = s.readLine()
+	if vowel, ok := turkish.LastVowel(s.Hometown); ok {
+		switch vowel {
+		case 'u', 'o', 'û':
+			s.aiResponse(s.render(stage7.VowelResponses["u o"]))
+		case 'ü', 'ö':
+			s.aiResponse(s.render(stage7.VowelResponses["ü ö"]))
+		case 'a', 'ı', 'â':
+			s.aiResponse(s.render(stage7.VowelResponses["a ı"]))
+		case 'e', 'i', 'î':
+			s.aiResponse(s.render(stage7.VowelResponses["e i"]))
+		}
+	}
+	s.laugh()
+	s.aiResponse(s.render(stage7.Conclusion, s.UserName))
+	return stage8ID
+}
+
+// stage6 prints a joke and a proverb.
+func (s *Session) stage6() StageID {
+	s.aiResponse(s.render(s.content.Stages.Stage6.JokeIntro))
+	s.sayJoke()
+	s.laugh()
+	proverbs := []string{
+		"yani sakla samanı gelir zamanı.",
+		"yani arkadaşlarımızı dikkatli seçmemiz lazım.",
+		"buradan alınacak ders: Göte giren şemsiye açılmaz..",
+	}
+	s.aiResponse(fmt.Sprintf("\n%s\n", proverbs[s.randomInt(len(proverbs))]))
+	s.laugh()
+	s.centerPrint("")
+	return stage7ID
+}
+
+// stage5 contains a series of random questions. Questions missing from
+// data.json are skipped.
+func (s *Session) stage5() StageID {
+	stage5 := s.content.Stages.Stage5
+	// Question 1: Eyes
+	if s.randomInt(2) == 1 {
+		if prompt, ok := stage5.prompt(0); ok {
+			s.askYesNo(s.render(prompt.Text, s.UserName), prompt)
+		}
+	}
+	// Question 2: Money
+	if s.randomInt(2) == 1 {
+		if prompt, ok := stage5.prompt(1); ok {
+			s.askYesNo(s.render(prompt.Text, s.UserName), prompt)
+		}
+	}
+	// Question 3: Name Origin
+	if s.randomInt(2) == 1 {
+		if prompt, ok := stage5.prompt(2); ok {
+			question := s.render(prompt.Text, s.UserName)
+			s.aiResponse(question)
+			s.userPrompt("? ")
+			s.answerFreely(question, prompt.Response)
+			s.laugh()
+		}
+	}
+	// Question 4: Holding a number
+	if s.randomInt(2) == 1 {
+		if prompt, ok := stage5.prompt(3); ok {
+			s.aiResponse(s.render(prompt.Text, s.UserName))
+			s.askYesNo(prompt.Text, prompt)
+		}
+	}
+	// Question 5: Nickname
+	if s.randomInt(2) == 1 {
+		if nickname := stage5.Nickname.of(s.UserName); nickname != "" {
+			s.aiResponse(s.render(stage5.Nickname.Text, s.UserName, nickname))
+			s.userPrompt("? ")
+			input := turkish.Lower(s.readYesNo())
+			if input == "e" {
+				s.aiResponse(s.render(stage5.Nickname.Yes))
+				s.laugh()
+			} else {
+				s.aiResponse(s.render(stage5.Nickname.No, nickname))
+				s.laugh()
+			}
+		}
+	}
+	// Question 6: How are you?
+	if s.randomInt(2) == 1 {
+		prompt, ok := stage5.prompt(4)
+		if ok {
+			s.aiResponse(s.render(prompt.Text, s.UserName))
+			s.userPrompt("? ")
+			input := turkish.Lower(s.readYesNo())
+			if input == "e" {
+				randChoice := s.randomInt(3)
+				if randChoice == 0 {
+					s.aiResponse(s.render(prompt.Yes.at(0)))
+				} else {
+					s.aiResponse(s.render(prompt.Yes.at(randChoice), s.UserName))
+				}
+			} else {
+				randChoice := s.randomInt(3)
+				if randChoice < 2 {
+					s.aiResponse(s.render(prompt.No.at(randChoice)))
+				} else {
+					question := s.render(prompt.No.at(2), s.UserName)
+					s.aiResponse(question)
+					s.answerFreely(question, prompt.No.at(3))
+				}
+			}
+			s.laugh()
+		}
+	}
+	// Question 7: Student
+	if s.randomInt(2) == 1 {
+		prompt, ok := stage5.prompt(5)
+		if ok {
+			s.aiResponse(s.render(prompt.Text, s.UserName))
+			s.userPrompt("? ")
+			input := turkish.Lower(s.readYesNo())
+			if input == "e" {
+				randChoice := s.randomInt(2)
+				s.aiResponse(s.render(prompt.Yes.at(randChoice)))
+			} else {
+				randChoice := s.randomInt(2)
+				if randChoice == 0 {
+					s.aiResponse(s.render(prompt.No.at(0)))
+				} else {
+					s.userPrompt(s.render(prompt.No.at(1)))
+					s.answerFreely(prompt.No.at(1), prompt.No.at(2))
+				}
+			}
+			s.laugh()
+		}
+	}
+	return stage6ID
+}
+
+// askYesNo asks a stage5 question with the given prompt line and gives the
+// response matching the e/h answer.
+func (s *Session) askYesNo(text string, prompt Prompt) {
+	s.userPrompt(text)
+	input := turkish.Lower(s.readYesNo())
+	if input == "e" {
+		s.aiResponse(s.render(prompt.Yes.at(0)))
+	} else {
+		s.aiResponse(s.render(prompt.No.at(0)))
+	}
+	s.laugh()
+}
+
+// stage4 asks for the user's weight and responds accordingly.
+func (s *Session) stage4() StageID {
+	var weight int
+	for {
+		s.userPrompt(s.render(s.content.Stages.Stage4.WeightPrompt))
+		input := s.readNumber()
+		var err error
+		weight, err = strconv.Atoi(input)
+		if err != nil {
+			s.aiResponse(s.render(s.content.Stages.Stage4.InvalidInput))
+			continue
+		}
+		s.Weight = weight
+		s.aiResponse(s.render(s.content.Stages.Stage4.WeightResponse, weight))
+		if weight <= 39 {
+			s.aiResponse(s.render(s.content.Stages.Stage4.WeightRanges[0].Text))
+			s.actDumb()
+		} else if weight >= 40 && weight <= 59 {
+			s.aiResponse(s.render(s.content.Stages.Stage4.WeightRanges[1].Text))
+			s.actDumb()
+		} else if weight >= 60 && weight <= 79 {
+			s.aiResponse(s.render(s.content.Stages.Stage4.WeightRanges[2].Text))
+			s.actDumb()
+		} else if weight >= 80 && weight <= 99 {
+			randChoice := s.randomInt(len(s.content.Stages.Stage4.WeightRanges[3].Variants))
+			s.aiResponse(s.render(s.content.Stages.Stage4.WeightRanges[3].Variants[randChoice]))
+			s.actDumb()
+		} else if weight >= 100 {
+			s.aiResponse(s.render(s.content.Stages.Stage4.WeightRanges[4].Text))
+			s.actDumb()
+		}
+		s.centerPrint("")
+		break
+	}
+	return stage5ID
+}
+
+// stage3 asks for the user's height and responds accordingly.
+func (s *Session) stage3() StageID {
+	s.userPrompt(s.render(s.content.Stages.Stage3.HeightPrompt))
+	var height int
+	for {
+		input := s.readNumber()
+		var err error
+		height, err = strconv.Atoi(input)
+		if err != nil {
+			s.aiResponse(s.render(s.content.Stages.Stage3.InvalidInput))
+			continue
+		}
+		s.Height = height
+		s.aiResponse(s.render(s.content.Stages.Stage3.HeightResponse, height))
+		if height <= 99 {
+			s.aiResponse(s.render(s.content.Stages.Stage3.HeightRanges[0].Text))
+		} else if height >= 100 && height <= 149 {
+			s.aiResponse(s.render(s.content.Stages.Stage3.HeightRanges[1].Text))
+		} else if height >= 150 && height <= 169 {
+			s.aiResponse(s.render(s.content.Stages.Stage3.HeightRanges[2].Text))
+		} else if height >= 170 && height <= 189 {
+			s.aiResponse(s.render(s.content.Stages.Stage3.HeightRanges[3].Text))
+		} else if height >= 190 && height <= 209 {
+			s.aiResponse(s.render(s.content.Stages.Stage3.HeightRanges[4].Text))
+		} else if height >= 210 {
+			s.aiResponse(s.render(s.content.Stages.Stage3.HeightRanges[5].Text))
+			continue
+		}
+		s.centerPrint("")
+		break
+	}
+	return stage4ID
+}
+
+// stage2 asks for the user's age and responds accordingly.
+func (s *Session) stage2() StageID {
+	s.userPrompt(s.render(s.content.Stages.Stage2.AgePrompt))
+	var age int
+	for {
+		input := s.readNumber()
+		var err error
+		age, err = strconv.Atoi(input)
+		if err != nil {
+			s.aiResponse(s.render(s.content.Stages.Stage2.InvalidInput))
+			continue
+		}
+		s.Age = age
+		s.aiResponse(s.render(s.content.Stages.Stage2.AgeResponse, age))
+		if age <= 4 {
+			s.aiResponse(s.render(s.content.Stages.Stage2.AgeRanges[0].Text))
+		} else if age >= 5 && age <= 9 {
+			s.userPrompt(s.render(s.content.Stages.Stage2.AgeRanges[1].Text))
+			choice := turkish.Lower(s.readYesNo())
+			if choice == "e" {
+				s.aiResponse(s.render(s.content.Stages.Stage2.AgeRanges[1].Yes))
+			} else {
+				s.aiResponse(s.render(s.content.Stages.Stage2.AgeRanges[1].No))
+			}
+		} else if age >= 10 && age <= 17 {
+			s.aiResponse(s.render(s.content.Stages.Stage2.AgeRanges[2].Text))
+		} else if age >= 18 && age <= 24 {
+			s.userPrompt(s.render(s.content.Stages.Stage2.AgeRanges[3].Text))
+			choice := turkish.Lower(s.readYesNo())
+			if choice == "e" {
+				s.aiResponse(s.render(s.content.Stages.Stage2.AgeRanges[3].Yes))
+			} else {
+				s.aiResponse(s.render(s.content.Stages.Stage2.AgeRanges[3].No))
+			}
+		} else if age >= 25 && age <= 39 {
+			s.aiResponse(s.render(s.content.Stages.Stage2.AgeRanges[4].Text))
+		} else if age >= 40 && age <= 59 {
+			s.aiResponse(s.render(s.content.Stages.Stage2.AgeRanges[5].Text))
+		} else if age >= 60 && age <= 98 {
+			s.aiResponse(s.render(s.content.Stages.Stage2.AgeRanges[6].Text))
+		} else if age >= 99 {
+			s.aiResponse(s.render(s.content.Stages.Stage2.AgeRanges[7].Text))
+			continue
+		}
+		s.centerPrint("")
+		break
+	}
+	return stage3ID
+}
+
+// stage1 asks for the user's name and starts the conversation. A login
+// name, when there is one, is offered first.
+func (s *Session) stage1() StageID {
+	stage1 := s.content.Stages.Stage1
+	name := ""
+	if s.LoginName != "" {
+		if slices.ContainsFunc(stage1.GenericLogins, func(g string) bool { return turkish.EqualFold(g, s.LoginName) }) {
+			s.aiResponse(s.render(stage1.GenericLogin, s.LoginName))
+		} else {
+			s.userPrompt(s.render(stage1.LoginPrompt, s.LoginName))
+			if answer := turkish.Lower(s.readYesNo()); answer == "e" || answer == "" {
+				name = s.LoginName
+			}
+		}
+	}
+	if name == "" {
+		s.userPrompt(s.render(stage1.NamePrompt))
+		name = s.readLine()
+	}
+	s.UserName = name
+	s.aiResponse(s.render(stage1.Responses.Intro, s.UserName))
+	return stage2ID
+}
+
+// stage0 is the initial welcome and introduction.
+func (s *Session) stage0() StageID {
+	fmt.Fprintln(s.out)
+	s.centerPrint(s.paint(ColorCyan, "Merhaba, hoş geldin."))
+	s.sleep(1 * time.Second)
+	s.centerPrint(s.paint(ColorCyan, "Ben yeni nesil bir terminal arayüzüyüm."))
+	s.sleep(1 * time.Second)
+	return stage1ID
+}
@@ -0,0 +1,126 @@
+package karabasan
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"text/template"
+
+	"k.go/karabasan/turkish"
+)
+
+// Content lines are text/template templates before they are fmt formats:
+// a line can say {{ablative .Hometown}} or {{li .Hometown}}sun and get the
+// suffix right for whatever the user typed. The data is templateData.
+var templateFuncs = template.FuncMap{
+	"li":       turkish.Li,
+	"ablative": turkish.Ablative,
+	"locative": turkish.Locative,
+	"dative":   turkish.Dative,
+	"genitive": turkish.Genitive,
+	"upper":    turkish.Upper,
+	"lower":    turkish.Lower,
+	"title":    turkish.Title,
+}
+
+// templateData is what content templates can refer to. Name and Hometown
+// are title cased, as the proper nouns they are, so that case endings go
+// after an apostrophe.
+type templateData struct {
+	Name     string
+	Hometown string
+	Age      int
+	Height   int
+	Weight   int
+}
+
+// parsedTemplates caches the templates of the content lines by their text.
+var parsedTemplates sync.Map
+
+// parseTemplate parses a content line, once.
+func parseTemplate(text string) (*template.Template, error) {
+	if t, ok := parsedTemplates.Load(text); ok {
+		return t.(*template.Template), nil
+	}
+	t, err := template.New("").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	parsedTemplates.Store(text, t)
+	return t, nil
+}
+
+// render fills in a content line: first its template actions, then its fmt
+// verbs with args. User answers only ever go in through the template data
+// and args, never into the template itself.
+func (s *Session) render(text string, args ...any) string {
+	if strings.Contains(text, "{{") {
+		text = s.execute(text, len(args) > 0)
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// execute runs the template of a content line. When the result is still to
+// be formatted, the answers have their % signs doubled so that fmt leaves
+// them alone. A line that does not run is said as it is written.
+func (s *Session) execute(text string, formatted bool) string {
+	t, err := parseTemplate(text)
+	if err != nil {
+		return text
+	}
+	escape := func(v string) string { return v }
+	if formatted {
+		escape = func(v string) string { return strings.ReplaceAll(v, "%", "%%") }
+	}
+	var b strings.Builder
+	err = t.Execute(&b, templateData{
+		Name:     escape(turkish.Title(s.UserName)),
+		Hometown: escape(turkish.Title(s.Hometown)),
+		Age:      s.Age,
+		Height:   s.Height,
+		Weight:   s.Weight,
+	})
+	if err != nil {
+		return text
+	}
+	return b.String()
+}
+
+// checkTemplates parses every string of the content that holds a template
+// action, so that a broken line is reported when the content is loaded.
+func checkTemplates(v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.String:
+		if text := v.String(); strings.Contains(text, "{{") {
+			if _, err := parseTemplate(text); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			if err := checkTemplates(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := checkTemplates(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key())); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if field := v.Type().Field(i); field.IsExported() {
+				if err := checkTemplates(v.Field(i), path+"."+field.Name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package karabasan
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	content, err := LoadContent("../data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewTerminalSession(content, strings.NewReader(""), &strings.Builder{})
+	s.UserName, s.Hometown = "me%met", "ürgüp"
+
+	if got, want := s.render("{{.Name}} {{ablative .Hometown}} %s", "geldi"), "Me%met Ürgüp'ten geldi"; got != want {
+		t.Errorf("render = %q, want %q", got, want)
+	}
+	if got, want := s.render("selam {{.Name}}, {{li .Hometown}}"), "selam Me%met, Ürgüplü"; got != want {
+		t.Errorf("render = %q, want %q", got, want)
+	}
+	if got, want := s.render("%%%d", 100), "%100"; got != want {
+		t.Errorf("render = %q, want %q", got, want)
+	}
+
+	content.Stages.Stage7.VowelResponses["a ı"] = "pis {{li .Memleket}}!"
+	if got := s.render(content.Stages.Stage7.VowelResponses["a ı"]); got != "pis {{li .Memleket}}!" {
+		t.Errorf("a line that does not run was said as %q", got)
+	}
+	content.Stages.Stage7.VowelResponses["a ı"] = "pis {{li .Hometown}!"
+	if err := checkTemplates(reflect.ValueOf(content).Elem(), "content"); err == nil {
+		t.Error("a broken template was not reported")
+	}
+}
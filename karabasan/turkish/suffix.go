@@ -0,0 +1,182 @@
+package turkish
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The suffixes below follow Turkish vowel harmony: a two-way suffix takes
+// a or e after the word's last vowel, a four-way one takes ı, i, u or ü.
+// A suffix starting with d turns to t after a voiceless consonant, one of
+// "fıstıkçı şahap". Proper nouns, written with a capital, get the case
+// endings after an apostrophe: Bursa'dan, Sinop'ta, Kars'ın.
+
+// voicelessConsonants are the letters of "fıstıkçı şahap".
+const voicelessConsonants = "fstkçşhp"
+
+// lastSound returns the last vowel of word and whether the word ends in
+// it. A word without vowels, such as TBMM, is read out letter by letter,
+// and Turkish letter names end in e: TBMM'ye.
+func lastSound(word string) (vowel rune, endsInVowel bool) {
+	letters := []rune(Lower(strings.TrimRightFunc(word, func(r rune) bool { return !unicode.IsLetter(r) })))
+	for i := len(letters) - 1; i >= 0; i-- {
+		if IsVowel(letters[i]) {
+			return letters[i], i == len(letters)-1
+		}
+	}
+	return 'e', true
+}
+
+// LastVowel returns the last vowel of word, lowercased, or false when the
+// word has none.
+func LastVowel(word string) (vowel rune, ok bool) {
+	for _, r := range slices.Backward([]rune(Lower(word))) {
+		if IsVowel(r) {
+			return r, true
+		}
+	}
+	return 0, false
+}
+
+// harmony2 picks the a or e form of a suffix for word.
+func harmony2(word string) rune {
+	vowel, _ := lastSound(word)
+	if strings.ContainsRune("aıouâû", vowel) {
+		return 'a'
+	}
+	return 'e'
+}
+
+// harmony4 picks the ı, i, u or ü form of a suffix for word.
+func harmony4(word string) rune {
+	vowel, _ := lastSound(word)
+	switch vowel {
+	case 'a', 'ı', 'â':
+		return 'ı'
+	case 'e', 'i', 'î':
+		return 'i'
+	case 'o', 'u', 'û':
+		return 'u'
+	}
+	return 'ü'
+}
+
+// endsInVowel reports whether the last sound of word is a vowel.
+func endsInVowel(word string) bool {
+	_, vowel := lastSound(word)
+	return vowel
+}
+
+// endsVoiceless reports whether word ends in one of "fıstıkçı şahap".
+func endsVoiceless(word string) bool {
+	if endsInVowel(word) {
+		return false
+	}
+	word = strings.TrimRightFunc(word, func(r rune) bool { return !unicode.IsLetter(r) })
+	last, _ := utf8.DecodeLastRuneInString(Lower(word))
+	return strings.ContainsRune(voicelessConsonants, last)
+}
+
+// isProperNoun reports whether word is written with a capital.
+func isProperNoun(word string) bool {
+	first, _ := utf8.DecodeRuneInString(word)
+	return unicode.IsUpper(first)
+}
+
+// caseEnding attaches a case suffix to word, after an apostrophe when the
+// word is a proper noun.
+func caseEnding(word, suffix string) string {
+	if word == "" {
+		return ""
+	}
+	if isProperNoun(word) {
+		return word + "'" + suffix
+	}
+	return word + suffix
+}
+
+// dTo returns the d or t form of a suffix starting with d.
+func dTo(word string) string {
+	if endsVoiceless(word) {
+		return "t"
+	}
+	return "d"
+}
+
+// Li adds the -lı suffix, "from" or "with": Ankaralı, Rizeli,
+// Erzurumlu, Üsküdarlı. It is a derivation, so it takes no apostrophe.
+func Li(word string) string {
+	if word == "" {
+		return ""
+	}
+	return word + "l" + string(harmony4(word))
+}
+
+// Ablative adds the -dan suffix, "from": Bursa'dan, Sinop'tan, evden.
+func Ablative(word string) string {
+	return caseEnding(word, dTo(word)+string(harmony2(word))+"n")
+}
+
+// Locative adds the -da suffix, "in": İzmir'de, Kars'ta, evde.
+func Locative(word string) string {
+	return caseEnding(word, dTo(word)+string(harmony2(word)))
+}
+
+// Dative adds the -a suffix, "to": Ankara'ya, Van'a, eve. Common nouns of
+// more than one syllable soften a final p, ç, t or k: kitaba, ağaca.
+func Dative(word string) string {
+	suffix := string(harmony2(word))
+	if endsInVowel(word) {
+		return caseEnding(word, "y"+suffix)
+	}
+	if !isProperNoun(word) {
+		word = soften(word)
+	}
+	return caseEnding(word, suffix)
+}
+
+// Genitive adds the -ın suffix, "of": Bursa'nın, Kars'ın, evin.
+func Genitive(word string) string {
+	suffix := string(harmony4(word)) + "n"
+	if endsInVowel(word) {
+		suffix = "n" + suffix
+	}
+	if !isProperNoun(word) && !endsInVowel(word) {
+		word = soften(word)
+	}
+	return caseEnding(word, suffix)
+}
+
+// soften turns the final p, ç, t or k of a word of more than one syllable
+// into b, c, d or ğ before a vowel: kitap→kitab. A final nk becomes ng
+// whatever the length, renk→reng, but other one syllable words keep theirs:
+// top→topa.
+func soften(word string) string {
+	if stem, ok := strings.CutSuffix(word, "nk"); ok {
+		return stem + "ng"
+	}
+	syllables := 0
+	for _, r := range Lower(word) {
+		if IsVowel(r) {
+			syllables++
+		}
+	}
+	if syllables < 2 {
+		return word
+	}
+	last, size := utf8.DecodeLastRuneInString(word)
+	stem := word[:len(word)-size]
+	switch last {
+	case 'p':
+		return stem + "b"
+	case 'ç':
+		return stem + "c"
+	case 't':
+		return stem + "d"
+	case 'k':
+		return stem + "ğ"
+	}
+	return word
+}
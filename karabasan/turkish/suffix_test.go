@@ -0,0 +1,43 @@
+package turkish
+
+import "testing"
+
+func TestSuffixes(t *testing.T) {
+	for _, tt := range []struct {
+		suffix func(string) string
+		word   string
+		want   string
+	}{
+		{Li, "Ankara", "Ankaralı"},
+		{Li, "Rize", "Rizeli"},
+		{Li, "Erzurum", "Erzurumlu"},
+		{Li, "Üsküdar", "Üsküdarlı"},
+		{Li, "Ürgüp", "Ürgüplü"},
+		{Ablative, "Bursa", "Bursa'dan"},
+		{Ablative, "Sinop", "Sinop'tan"},
+		{Ablative, "Ürgüp", "Ürgüp'ten"},
+		{Ablative, "ISPARTA", "ISPARTA'dan"},
+		{Ablative, "TBMM", "TBMM'den"},
+		{Ablative, "ev", "evden"},
+		{Locative, "Kars", "Kars'ta"},
+		{Locative, "İzmir", "İzmir'de"},
+		{Locative, "Bolu", "Bolu'da"},
+		{Dative, "Ankara", "Ankara'ya"},
+		{Dative, "Van", "Van'a"},
+		{Dative, "TBMM", "TBMM'ye"},
+		{Dative, "kitap", "kitaba"},
+		{Dative, "ağaç", "ağaca"},
+		{Dative, "renk", "renge"},
+		{Dative, "top", "topa"},
+		{Genitive, "Bursa", "Bursa'nın"},
+		{Genitive, "Kars", "Kars'ın"},
+		{Genitive, "Ürgüp", "Ürgüp'ün"},
+		{Genitive, "ev", "evin"},
+		{Genitive, "kitap", "kitabın"},
+		{Ablative, "", ""},
+	} {
+		if got := tt.suffix(tt.word); got != tt.want {
+			t.Errorf("suffix of %q = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
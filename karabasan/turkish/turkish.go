@@ -0,0 +1,117 @@
+// Package turkish handles text the Turkish way: case mapping with dotted
+// and dotless i, NFC normalisation, matching that forgives missing
+// diacritics, and the suffixes that follow vowel harmony.
+package turkish
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalize puts s in NFC, so that a letter typed as a base letter and a
+// combining mark, such as I and a combining dot, is the letter itself: İ.
+// An i with a combining dot, what strings.ToLower makes of İ, becomes a
+// plain i.
+func Normalize(s string) string {
+	return strings.ReplaceAll(norm.NFC.String(s), "i\u0307", "i")
+}
+
+// Lower lowercases s with the Turkish rules: I→ı and İ→i.
+func Lower(s string) string {
+	return strings.ToLowerSpecial(unicode.TurkishCase, Normalize(s))
+}
+
+// Upper uppercases s with the Turkish rules: ı→I and i→İ.
+func Upper(s string) string {
+	return strings.ToUpperSpecial(unicode.TurkishCase, Normalize(s))
+}
+
+// Capitalize uppercases the first letter of s and leaves the rest alone.
+func Capitalize(s string) string {
+	s = Normalize(s)
+	first, size := utf8.DecodeRuneInString(s)
+	if first == utf8.RuneError {
+		return s
+	}
+	return Upper(string(first)) + s[size:]
+}
+
+// Title uppercases the first letter of every word of s and lowercases the
+// others: "ıSPARTA" and "ısparta" are both "Isparta", "izmir" is "İzmir".
+// An apostrophe does not start a word: "istanbul'dan" is "İstanbul'dan".
+func Title(s string) string {
+	var b strings.Builder
+	start := true
+	for _, r := range Lower(s) {
+		if start && unicode.IsLetter(r) {
+			b.WriteString(Upper(string(r)))
+		} else {
+			b.WriteRune(r)
+		}
+		start = unicode.IsSpace(r) || r == '-'
+	}
+	return b.String()
+}
+
+// Fold lowercases s and takes the diacritics off its letters, ı included,
+// for matching what was typed on a keyboard without Turkish letters:
+// "GÖRÜŞÜRÜZ", "görüşürüz" and "gorusuruz" all fold to "gorusuruz".
+func Fold(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(Lower(s)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case r == 'ı':
+			b.WriteRune('i')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// EqualFold reports whether a and b are the same text up to Turkish case
+// and diacritics.
+func EqualFold(a, b string) bool {
+	return Fold(a) == Fold(b)
+}
+
+// vowels are the Turkish vowels, with the circumflexed ones of loanwords
+// such as kâr and hâlâ.
+const vowels = "aeıioöuüâîû"
+
+// IsVowel reports whether r is a Turkish vowel, in either case.
+func IsVowel(r rune) bool {
+	return strings.ContainsRune(vowels, unicode.TurkishCase.ToLower(r))
+}
+
+// Syllables splits word into its syllables by the Turkish rule: of the
+// consonants between two vowels, only the last one starts the second
+// syllable: Meh-met, Ay-şe, Mus-ta-fa, İb-ra-him, A-li. A word without
+// vowels is one syllable.
+func Syllables(word string) []string {
+	if word == "" {
+		return nil
+	}
+	letters := []rune(word)
+	var syllables []string
+	start, prev := 0, -1
+	for i, r := range letters {
+		if !IsVowel(r) {
+			continue
+		}
+		if prev >= 0 {
+			cut := i
+			if i-prev > 1 {
+				cut = i - 1
+			}
+			syllables = append(syllables, string(letters[start:cut]))
+			start = cut
+		}
+		prev = i
+	}
+	return append(syllables, string(letters[start:]))
+}
@@ -0,0 +1,83 @@
+package turkish
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCase(t *testing.T) {
+	for _, tt := range []struct {
+		in, lower, upper, title string
+	}{
+		{"ISPARTA", "ısparta", "ISPARTA", "Isparta"},
+		{"İzmir", "izmir", "İZMİR", "İzmir"},
+		{"İZMİR", "izmir", "İZMİR", "İzmir"},
+		{"i̇zmir", "izmir", "İZMİR", "İzmir"},
+		{"mehmet ali", "mehmet ali", "MEHMET ALİ", "Mehmet Ali"},
+		{"istanbul'DAN", "istanbul'dan", "İSTANBUL'DAN", "İstanbul'dan"},
+		{"afyon-karahisar", "afyon-karahisar", "AFYON-KARAHİSAR", "Afyon-Karahisar"},
+	} {
+		if got := Lower(tt.in); got != tt.lower {
+			t.Errorf("Lower(%q) = %q, want %q", tt.in, got, tt.lower)
+		}
+		if got := Upper(tt.in); got != tt.upper {
+			t.Errorf("Upper(%q) = %q, want %q", tt.in, got, tt.upper)
+		}
+		if got := Title(tt.in); got != tt.title {
+			t.Errorf("Title(%q) = %q, want %q", tt.in, got, tt.title)
+		}
+	}
+	if got := Capitalize("ıSPARTA"); got != "ISPARTA" {
+		t.Errorf("Capitalize = %q", got)
+	}
+}
+
+func TestEqualFold(t *testing.T) {
+	for _, pair := range [][2]string{
+		{"GÖRÜŞÜRÜZ", "gorusuruz"},
+		{"ISPARTA", "isparta"},
+		{"İZMİR", "izmir"},
+		{"Çağlar", "caglar"},
+		{"hâlâ", "hala"},
+		{"DOS", "dos"},
+	} {
+		if !EqualFold(pair[0], pair[1]) {
+			t.Errorf("EqualFold(%q, %q) = false", pair[0], pair[1])
+		}
+	}
+	if EqualFold("kolay", "zor") {
+		t.Error("EqualFold(kolay, zor) = true")
+	}
+}
+
+func TestIsVowel(t *testing.T) {
+	for _, r := range "aeıioöuüAEIİOÖUÜâ" {
+		if !IsVowel(r) {
+			t.Errorf("IsVowel(%q) = false", r)
+		}
+	}
+	for _, r := range "bçğşyBÇĞŞY" {
+		if IsVowel(r) {
+			t.Errorf("IsVowel(%q) = true", r)
+		}
+	}
+}
+
+func TestSyllables(t *testing.T) {
+	for word, want := range map[string][]string{
+		"Mehmet":  {"Meh", "met"},
+		"Ayşe":    {"Ay", "şe"},
+		"Mustafa": {"Mus", "ta", "fa"},
+		"İbrahim": {"İb", "ra", "him"},
+		"Ali":     {"A", "li"},
+		"saat":    {"sa", "at"},
+		"Türkçe":  {"Türk", "çe"},
+		"Can":     {"Can"},
+		"TBMM":    {"TBMM"},
+		"":        nil,
+	} {
+		if got := Syllables(word); !slices.Equal(got, want) {
+			t.Errorf("Syllables(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package karabasan
+
+import (
+	"strconv"
+	"strings"
+)
+
+// XOX is the content of the tic-tac-toe stage.
+type XOX struct {
+	Intro        string `json:"intro"`
+	MovePrompt   string `json:"movePrompt"`
+	InvalidCell  string `json:"invalidCell"`
+	OccupiedCell string `json:"occupiedCell"`
+	BotMove      string `json:"botMove"`
+	Win          string `json:"win"`
+	Lose         string `json:"lose"`
+	Draw         string `json:"draw"`
+}
+
+const (
+	xoxUser  = 'X'
+	xoxBot   = 'O'
+	xoxEmpty = ' '
+)
+
+// xoxLines are the rows, columns and diagonals of the board.
+var xoxLines = [8][3]int{
+	{0, 1, 2}, {3, 4, 5}, {6, 7, 8},
+	{0, 3, 6}, {1, 4, 7}, {2, 5, 8},
+	{0, 4, 8}, {2, 4, 6},
+}
+
+type xoxBoard [9]rune
+
+// winner returns the mark with three in a row, or xoxEmpty.
+func (b *xoxBoard) winner() rune {
+	for _, line := range xoxLines {
+		if b[line[0]] != xoxEmpty && b[line[0]] == b[line[1]] && b[line[1]] == b[line[2]] {
+			return b[line[0]]
+		}
+	}
+	return xoxEmpty
+}
+
+// freeCells lists the empty cells in board order.
+func (b *xoxBoard) freeCells() []int {
+	var cells []int
+	for i, c := range b {
+		if c == xoxEmpty {
+			cells = append(cells, i)
+		}
+	}
+	return cells
+}
+
+// String draws the board, showing cell numbers in the empty cells.
+func (b *xoxBoard) String() string {
+	var rows []string
+	for r := 0; r < 3; r++ {
+		var cells []string
+		for c := 0; c < 3; c++ {
+			i := r*3 + c
+			if b[i] == xoxEmpty {
+				cells = append(cells, " "+strconv.Itoa(i+1)+" ")
+			} else {
+				cells = append(cells, " "+string(b[i])+" ")
+			}
+		}
+		rows = append(rows, strings.Join(cells, "|"))
+	}
+	return strings.Join(rows, "\n---+---+---\n")
+}
+
+// minimax scores the board for the bot: positive when O wins, negative when
+// X wins, preferring quicker wins and slower losses.
+func minimax(b *xoxBoard, turn rune, depth int) int {
+	switch b.winner() {
+	case xoxBot:
+		return 10 - depth
+	case xoxUser:
+		return depth - 10
+	}
+	free := b.freeCells()
+	if len(free) == 0 {
+		return 0
+	}
+	best := -100
+	if turn == xoxUser {
+		best = 100
+	}
+	for _, i := range free {
+		b[i] = turn
+		if turn == xoxBot {
+			best = max(best, minimax(b, xoxUser, depth+1))
+		} else {
+			best = min(best, minimax(b, xoxBot, depth+1))
+		}
+		b[i] = xoxEmpty
+	}
+	return best
+}
+
+// botCell picks Karabasan's move: a random one with the difficulty's blunder
+// chance, otherwise the best minimax move.
+func (s *Session) botCell(b *xoxBoard, blunderChance float64) int {
+	free := b.freeCells()
+	if s.rng.Float64() < blunderChance {
+		return free[s.randomInt(len(free))]
+	}
+	bestCell, bestScore := free[0], -100
+	for _, i := range free {
+		b[i] = xoxBot
+		score := minimax(b, xoxUser, 1)
+		b[i] = xoxEmpty
+		if score > bestScore {
+			bestCell, bestScore = i, score
+		}
+	}
+	return bestCell
+}
+
+// stageXOX is tic-tac-toe against Karabasan; the user plays X and moves first.
+func (s *Session) stageXOX() StageID {
+	xox := s.content.Stages.XOX
+	blunderChance := s.playedDifficulty().BlunderChance
+	var board xoxBoard
+	for i := range board {
+		board[i] = xoxEmpty
+	}
+
+	s.aiResponse(s.render(xox.Intro, s.UserName))
+	for board.winner() == xoxEmpty && len(board.freeCells()) > 0 {
+		s.centerPrint(padBlock(board.String()))
+		s.userPrompt(s.render(xox.MovePrompt))
+		input := s.readNumber()
+		cell, err := strconv.Atoi(input)
+		if err != nil || cell < 1 || cell > 9 {
+			s.aiResponse(s.render(xox.InvalidCell))
+			continue
+		}
+		if board[cell-1] != xoxEmpty {
+			s.aiResponse(s.render(xox.OccupiedCell))
+			continue
+		}
+		board[cell-1] = xoxUser
+		if board.winner() != xoxEmpty || len(board.freeCells()) == 0 {
+			break
+		}
+		bot := s.botCell(&board, blunderChance)
+		board[bot] = xoxBot
+		s.aiResponse(s.render(xox.BotMove, bot+1))
+	}
+	s.centerPrint(padBlock(board.String()))
+
+	switch board.winner() {
+	case xoxBot:
+		s.aiResponse(s.render(xox.Win))
+		s.laugh()
+		s.Round.XOXResult = "lose"
+	case xoxUser:
+		s.aiResponse(s.render(xox.Lose))
+		s.swear()
+		s.Round.XOXResult = "win"
+	default:
+		s.aiResponse(s.render(xox.Draw))
+		s.Round.XOXResult = "draw"
+	}
+
+	return stage10ID
+}
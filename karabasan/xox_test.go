@@ -0,0 +1,89 @@
+package karabasan
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// xoxBoardOf reads a board written row by row, with . for an empty cell.
+func xoxBoardOf(cells string) xoxBoard {
+	var b xoxBoard
+	for i, c := range strings.ReplaceAll(cells, " ", "") {
+		if c == '.' {
+			c = xoxEmpty
+		}
+		b[i] = c
+	}
+	return b
+}
+
+func TestXOXWinner(t *testing.T) {
+	for cells, want := range map[string]rune{
+		"... ... ...": xoxEmpty,
+		"XXX OO. ...": xoxUser,
+		"O.. O.X O.X": xoxBot,
+		"X.O .XO ..X": xoxUser,
+		"X.O .OX O..": xoxBot,
+		"XOX XOO OXX": xoxEmpty,
+		"XX. OO. ...": xoxEmpty,
+	} {
+		b := xoxBoardOf(cells)
+		if got := b.winner(); got != want {
+			t.Errorf("winner(%s) = %q, want %q", cells, got, want)
+		}
+	}
+}
+
+func TestXOXBotTakesWinsAndBlocks(t *testing.T) {
+	s := NewTerminalSession(nil, strings.NewReader(""), io.Discard)
+	for _, tc := range []struct {
+		name  string
+		cells string
+		want  int
+	}{
+		{"takes the row", "X.X OO. X..", 5},
+		{"takes the diagonal", "OX. XO. X..", 8},
+		{"wins before blocking", "XX. OO. X..", 5},
+		{"blocks the row", "XX. .O. ...", 2},
+		{"blocks the column", "X.. XO. ...", 6},
+		{"blocks the diagonal", "X.. .X. O..", 8},
+	} {
+		b := xoxBoardOf(tc.cells)
+		if got := s.botCell(&b, 0); got != tc.want {
+			t.Errorf("%s: botCell(%s) = %d, want %d", tc.name, tc.cells, got+1, tc.want+1)
+		}
+	}
+}
+
+// TestXOXBotNeverLoses plays every sequence of X moves against the bot
+// without blunders.
+func TestXOXBotNeverLoses(t *testing.T) {
+	s := NewTerminalSession(nil, strings.NewReader(""), io.Discard)
+	var games int
+	var play func(b xoxBoard, moves []int)
+	play = func(b xoxBoard, moves []int) {
+		for _, cell := range b.freeCells() {
+			next := b
+			next[cell] = xoxUser
+			played := append(moves[:len(moves):len(moves)], cell+1)
+			if next.winner() == xoxUser {
+				t.Fatalf("X won with the moves %v:\n%s", played, next.String())
+			}
+			if len(next.freeCells()) == 0 {
+				games++
+				continue
+			}
+			next[s.botCell(&next, 0)] = xoxBot
+			if next.winner() == xoxBot || len(next.freeCells()) == 0 {
+				games++
+				continue
+			}
+			play(next, played)
+		}
+	}
+	play(xoxBoardOf("... ... ..."), nil)
+	if games == 0 {
+		t.Fatal("no game was played")
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import "unicode/utf8"
+
+// lineEditor turns the keystrokes of a terminal in character mode into
+// whole lines, echoing and erasing as the terminal itself would in line
+// mode. The telnet and SSH front ends feed it what the user types.
+type lineEditor struct {
+	echo   func([]byte)
+	lastCR bool
+	line   []byte
+	ready  []byte
+}
+
+// key edits the current line with one typed byte.
+func (e *lineEditor) key(b byte) {
+	lastCR := e.lastCR
+	e.lastCR = false
+	switch {
+	case b == '\r':
+		e.lastCR = true
+		e.finishLine()
+	case b == '\n' || b == 0:
+		// CR LF and CR NUL end a single line.
+		if !lastCR {
+			e.finishLine()
+		}
+	case b == 0x7f || b == '\b':
+		if len(e.line) > 0 {
+			_, size := utf8.DecodeLastRune(e.line)
+			e.line = e.line[:len(e.line)-size]
+			e.echo([]byte("\b \b"))
+		}
+	case b < 0x20:
+		// Other control characters have no place in an answer.
+	default:
+		e.line = append(e.line, b)
+		e.echo([]byte{b})
+	}
+}
+
+func (e *lineEditor) finishLine() {
+	e.echo([]byte("\r\n"))
+	e.ready = append(e.ready, e.line...)
+	e.ready = append(e.ready, '\n')
+	e.line = e.line[:0]
+}
+
+// read hands out finished lines; it returns 0 when none is ready yet.
+func (e *lineEditor) read(p []byte) int {
+	n := copy(p, e.ready)
+	e.ready = e.ready[n:]
+	return n
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"k.go/karabasan"
+)
+
+// newJSONLSession creates a session that reads its inputs as JSON lines
+// from r and writes its events as JSON lines to w, and renders nothing.
+func newJSONLSession(content *karabasan.Content, r io.Reader, w io.Writer) *karabasan.Session {
+	events := jsonlEvents(w)
+	var s *karabasan.Session
+	in := newJSONLInput(r, func(reason string) {
+		events(karabasan.Event{Version: karabasan.ProtocolVersion, Type: karabasan.EventError, Stage: s.Stage, Text: reason})
+	})
+	s = karabasan.NewTerminalSession(content, in, io.Discard)
+	s.Plain = true
+	s.Events = events
+	return s
+}
+
+// jsonlEvents writes every event as one line of JSON to w.
+func jsonlEvents(w io.Writer) func(karabasan.Event) {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return func(e karabasan.Event) {
+		enc.Encode(e)
+	}
+}
+
+// jsonlInput reads {"type":"input","text":...} lines and gives the session
+// their texts, one per line. Lines it cannot use are reported as error
+// events and skipped.
+type jsonlInput struct {
+	lines   *bufio.Scanner
+	invalid func(reason string)
+	pending []byte
+}
+
+func newJSONLInput(r io.Reader, invalid func(reason string)) *jsonlInput {
+	return &jsonlInput{lines: bufio.NewScanner(r), invalid: invalid}
+}
+
+func (r *jsonlInput) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if !r.lines.Scan() {
+			if err := r.lines.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		line := bytes.TrimSpace(r.lines.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var msg struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(line, &msg); err != nil {
+			r.invalid("not a JSON object: " + err.Error())
+			continue
+		}
+		if msg.Type != "input" {
+			r.invalid(fmt.Sprintf("unknown message type %q", msg.Type))
+			continue
+		}
+		text := strings.NewReplacer("\r", " ", "\n", " ").Replace(msg.Text)
+		r.pending = []byte(text + "\n")
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
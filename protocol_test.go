@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"k.go/karabasan"
+	"k.go/karabasan/clocktest"
+)
+
+// runProtocol plays a session on JSON lines input and returns the events
+// it wrote, decoded.
+func runProtocol(t *testing.T, seed uint64, input string) []karabasan.Event {
+	t.Helper()
+	content, err := karabasan.LoadContent("data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	s := newJSONLSession(content, strings.NewReader(input), &out)
+	s.Clock = clocktest.New()
+	s.SetSeed(seed)
+	if err := s.Run(karabasan.FirstStage); err != karabasan.ErrInputClosed {
+		t.Fatalf("Run = %v, want the input to run out", err)
+	}
+
+	var events []karabasan.Event
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var e karabasan.Event
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Version != karabasan.ProtocolVersion {
+			t.Errorf("event %+v has version %d, want %d", e, e.Version, karabasan.ProtocolVersion)
+		}
+		if strings.Contains(e.Text, "\033") {
+			t.Errorf("event %+v has escape codes", e)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestProtocolReportsStagesAndPrompts(t *testing.T) {
+	events := runProtocol(t, 1, `{"type":"input","text":"Ali"}
+{"type":"input","text":"30"}
+{"type":"input","text":"175"}
+`)
+	var stages []karabasan.StageID
+	expects := map[karabasan.StageID]karabasan.InputKind{}
+	for _, e := range events {
+		switch e.Type {
+		case karabasan.EventStage:
+			stages = append(stages, e.Stage)
+		case karabasan.EventPrompt:
+			expects[e.Stage] = e.Expect
+		}
+	}
+	if want := []karabasan.StageID{"stage0", "stage1", "stage2", "stage3", "stage4"}; !slices.Equal(stages, want) {
+		t.Errorf("stages = %v, want %v", stages, want)
+	}
+	want := map[karabasan.StageID]karabasan.InputKind{"stage1": karabasan.InputText, "stage2": karabasan.InputNumber, "stage3": karabasan.InputNumber, "stage4": karabasan.InputNumber}
+	for id, kind := range want {
+		if expects[id] != kind {
+			t.Errorf("%s expects %q, want %q", id, expects[id], kind)
+		}
+	}
+	if last := events[len(events)-1]; last.Type != karabasan.EventEnd {
+		t.Errorf("last event is %+v, want the end", last)
+	}
+}
+
+func TestProtocolReportsThinkingBeforeAnswers(t *testing.T) {
+	events := runProtocol(t, 1, `{"type":"input","text":"Ali"}`+"\n")
+	for i, e := range events {
+		if e.Type == karabasan.EventSay && e.Stage == "stage1" {
+			if i == 0 || events[i-1].Type != karabasan.EventThinking || events[i-1].Millis <= 0 {
+				t.Errorf("%+v was not preceded by a thinking pause", e)
+			}
+			if e.Text != "Tanıştığıma memnun oldum, Ali. Hadi başlayalım." {
+				t.Errorf("stage1 said %q", e.Text)
+			}
+			return
+		}
+	}
+	t.Error("stage1 said nothing")
+}
+
+func TestProtocolSkipsBadInput(t *testing.T) {
+	events := runProtocol(t, 1, `{"type":"input","text":"Ali"}
+not json
+{"type":"resize"}
+{"type":"input","text":"multi\nline"}
+`)
+	var errors []string
+	for _, e := range events {
+		if e.Type == karabasan.EventError {
+			errors = append(errors, e.Text)
+		}
+		if e.Type == karabasan.EventSay && strings.Contains(e.Text, "multi") {
+			t.Errorf("a bad age was taken as %q", e.Text)
+		}
+	}
+	if len(errors) != 2 || !strings.Contains(errors[1], `"resize"`) {
+		t.Errorf("errors = %q, want the bad JSON and the unknown type", errors)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k.go/karabasan"
+)
+
+// castRecorder writes an asciinema v2 cast: a JSON header line followed by
+// one [time, type, data] event line per terminal write, input echo or resize.
+type castRecorder struct {
+	mu    sync.Mutex
+	f     *os.File
+	w     *bufio.Writer
+	clock karabasan.Clock
+	start time.Time
+	err   error
+}
+
+// castHeader is the first line of an asciinema v2 file.
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Command   string            `json:"command,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// newCastRecorder creates path and writes the header for a width x height
+// terminal. The command line, including the seed, goes into the header so
+// the recording can be reproduced. Events are stamped by clock, the
+// session's, so that a scripted run keeps its typing pauses.
+func newCastRecorder(path string, width, height int, command string, clock karabasan.Clock) (*castRecorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &castRecorder{f: f, w: bufio.NewWriter(f), clock: clock, start: clock.Now()}
+	header := castHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: r.start.Unix(),
+		Command:   command,
+		Title:     "karabasan",
+		Env:       map[string]string{"TERM": os.Getenv("TERM"), "SHELL": os.Getenv("SHELL")},
+	}
+	line, err := json.Marshal(header)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.w.Write(line)
+	r.w.WriteByte('\n')
+	return r, nil
+}
+
+// event appends one event line, stamped with the time since the recording started.
+func (r *castRecorder) event(kind, data string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	elapsed := r.clock.Now().Sub(r.start).Seconds()
+	line, err := json.Marshal([]any{elapsed, kind, data})
+	if err != nil {
+		r.err = err
+		return
+	}
+	r.w.Write(line)
+	r.err = r.w.WriteByte('\n')
+}
+
+// Write records terminal output. Bare newlines become CRLF, as the terminal
+// driver would have written them, so that players render lines correctly.
+func (r *castRecorder) Write(p []byte) (int, error) {
+	data := strings.ReplaceAll(string(p), "\r\n", "\n")
+	r.event("o", strings.ReplaceAll(data, "\n", "\r\n"))
+	return len(p), nil
+}
+
+// echoWriter records the user's input lines, both as input events and as
+// output, the way the terminal echoed them.
+type echoWriter struct{ r *castRecorder }
+
+func (e echoWriter) Write(p []byte) (int, error) {
+	data := strings.ReplaceAll(string(p), "\r\n", "\n")
+	e.r.event("i", data)
+	e.r.event("o", strings.ReplaceAll(data, "\n", "\r\n"))
+	return len(p), nil
+}
+
+// Echo returns a writer for input lines typed by the user.
+func (r *castRecorder) Echo() io.Writer {
+	return echoWriter{r}
+}
+
+// Resize records a terminal size change.
+func (r *castRecorder) Resize(cols, rows int) {
+	r.event("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// Close flushes the recording to disk.
+func (r *castRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if err := r.f.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
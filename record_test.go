@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k.go/karabasan"
+)
+
+// TestCastRecorder records a short scripted session the way main does and
+// checks the cast: the header, the answers as one input and one output event
+// each, a resize, and times taken from the session's clock.
+func TestCastRecorder(t *testing.T) {
+	content, err := karabasan.LoadContent("data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "session.cast")
+	clock := karabasan.NewInstantClock()
+	rec, err := newCastRecorder(path, 80, 24, "karabasan --seed 1", clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := karabasan.NewTerminalSession(content, strings.NewReader("Ali\ne\n"), io.MultiWriter(io.Discard, rec))
+	s.Clock = clock
+	s.Echo = rec.Echo()
+	s.SetSeed(1)
+	s.Run(karabasan.FirstStage)
+	rec.Resize(100, 30)
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lines := bufio.NewScanner(f)
+	if !lines.Scan() {
+		t.Fatal("the cast is empty")
+	}
+	var header castHeader
+	if err := json.Unmarshal(lines.Bytes(), &header); err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != 2 || header.Width != 80 || header.Height != 24 || header.Command != "karabasan --seed 1" {
+		t.Errorf("header = %+v", header)
+	}
+
+	var inputs []string
+	echoes := map[string]int{}
+	var resized string
+	last := 0.0
+	for lines.Scan() {
+		var event [3]any
+		if err := json.Unmarshal(lines.Bytes(), &event); err != nil {
+			t.Fatalf("event %s: %v", lines.Text(), err)
+		}
+		at, kind, data := event[0].(float64), event[1].(string), event[2].(string)
+		if at < last {
+			t.Errorf("event %s goes back in time", lines.Text())
+		}
+		last = at
+		switch kind {
+		case "i":
+			inputs = append(inputs, data)
+		case "o":
+			echoes[data]++
+		case "r":
+			resized = data
+		default:
+			t.Errorf("unknown event %s", lines.Text())
+		}
+	}
+	if strings.Join(inputs, "") != "Ali\ne\n" {
+		t.Errorf("input events = %q, want the two answers", inputs)
+	}
+	if echoes["Ali\r\n"] != 1 || echoes["e\r\n"] != 1 {
+		t.Errorf("answers echoed %d and %d times, want once each", echoes["Ali\r\n"], echoes["e\r\n"])
+	}
+	if resized != "100x30" {
+		t.Errorf("resize event = %q, want 100x30", resized)
+	}
+	if last == 0 {
+		t.Error("every event is at 0s, the session's typing pauses are lost")
+	}
+}
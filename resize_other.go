@@ -0,0 +1,7 @@
+//go:build !unix
+
+package main
+
+// watchResize is a no-op where there is no SIGWINCH; the size read at
+// startup is used for the whole session.
+func watchResize(fd int, fn func(cols, rows int)) {}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// watchResize calls fn with the new size of the terminal on fd every time
+// the window changes, until the process exits.
+func watchResize(fd int, fn func(cols, rows int)) {
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	go func() {
+		for range winch {
+			if cols, rows, err := term.GetSize(fd); err == nil {
+				fn(cols, rows)
+			}
+		}
+	}()
+}
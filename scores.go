@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"k.go/karabasan"
+	"k.go/karabasan/turkish"
+)
+
+// runScores implements "karabasan scores": the top entries per difficulty.
+func runScores(content *karabasan.Content, difficulty string, width int, args []string) {
+	cmd := flag.NewFlagSet("scores", flag.ExitOnError)
+	top := cmd.Int("n", 10, "number of entries to show per difficulty")
+	only := cmd.String("difficulty", difficulty, "show only this difficulty")
+	cmd.Parse(args)
+	if *top < 1 {
+		// Exit the way a flag that does not parse does.
+		fmt.Fprintf(cmd.Output(), "-n must be at least 1, not %d.\n", *top)
+		cmd.Usage()
+		os.Exit(2)
+	}
+
+	path, err := karabasan.DefaultScoresPath()
+	if err != nil {
+		fmt.Println("Error locating scores:", err)
+		os.Exit(1)
+	}
+	table, err := karabasan.ReadScores(path)
+	if err != nil {
+		fmt.Println("Error reading scores:", err)
+		os.Exit(1)
+	}
+
+	byDifficulty := map[string][]karabasan.ScoreEntry{}
+	var order []string
+	for _, d := range content.Difficulties {
+		order = append(order, d.Name)
+	}
+	for _, e := range table.Entries {
+		name := e.Difficulty
+		if d, ok := content.FindDifficulty(name); ok {
+			name = d.Name
+		} else if _, seen := byDifficulty[name]; !seen {
+			order = append(order, name)
+		}
+		byDifficulty[name] = append(byDifficulty[name], e)
+	}
+
+	for _, name := range order {
+		if *only != "" && !turkish.EqualFold(name, *only) {
+			continue
+		}
+		entries := byDifficulty[name]
+		if len(entries) == 0 {
+			continue
+		}
+		sort.SliceStable(entries, func(i, j int) bool { return karabasan.BetterScore(entries[i], entries[j]) })
+		if len(entries) > *top {
+			entries = entries[:*top]
+		}
+		fmt.Println(karabasan.ColorGreen + strings.Repeat("-", width) + karabasan.ColorReset)
+		fmt.Println(karabasan.ColorCyan + name + karabasan.ColorReset)
+		for i, e := range entries {
+			stage8 := fmt.Sprintf("%d", e.Stage8Count)
+			if !e.Stage8Solved {
+				stage8 = "-"
+			}
+			var flags []string
+			if e.Stage9Lied {
+				flags = append(flags, "yalancı")
+			}
+			if e.Stage9Accuse || e.RPSAccused {
+				flags = append(flags, "hileci")
+			}
+			fmt.Printf("%2d. %-16s %4s %4d  %s  %s\n", i+1, e.UserName, stage8, e.Stage9Count,
+				e.Time.Local().Format("2006-01-02 15:04"), strings.Join(flags, ","))
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"k.go/karabasan"
+)
+
+// loadScript reads the answers of a script file.
+func loadScript(path string) (*strings.Reader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(karabasan.ScriptAnswers(string(data))), nil
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"syscall"
+	"time"
+
+	"k.go/karabasan"
+)
+
+// connServer is the accept loop, connection limit and graceful shutdown
+// shared by the network front ends.
+type connServer struct {
+	// MaxConns is how many sessions run at once; further callers are
+	// turned away. Zero means no limit.
+	MaxConns int
+
+	mu       sync.Mutex
+	ln       net.Listener
+	conns    map[net.Conn]func()
+	closing  bool
+	sessions sync.WaitGroup
+}
+
+// listen makes ln the listener that Shutdown closes.
+func (cs *connServer) listen(ln net.Listener) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.ln = ln
+}
+
+// isClosing reports whether Shutdown has been called.
+func (cs *connServer) isClosing() bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.closing
+}
+
+// admit registers a new connection. full reports that it is over the
+// MaxConns limit and is only to be turned away; ok is false when the
+// server is shutting down and the connection should just be closed.
+// Admitted connections must be released.
+func (cs *connServer) admit(conn net.Conn) (full, ok bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.closing {
+		return false, false
+	}
+	if cs.conns == nil {
+		cs.conns = map[net.Conn]func(){}
+	}
+	full = cs.MaxConns > 0 && len(cs.conns) >= cs.MaxConns
+	if !full {
+		cs.conns[conn] = func() { conn.Close() }
+	}
+	cs.sessions.Add(1)
+	return full, true
+}
+
+// release forgets a connection registered by admit.
+func (cs *connServer) release(conn net.Conn) {
+	cs.mu.Lock()
+	delete(cs.conns, conn)
+	cs.mu.Unlock()
+	cs.sessions.Done()
+}
+
+// serve accepts connections on ln until shutdown and runs handle for each
+// in its own goroutine. full tells handle that the caller is over the
+// MaxConns limit and is only to be turned away.
+func (cs *connServer) serve(ln net.Listener, handle func(conn net.Conn, full bool)) error {
+	cs.listen(ln)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if cs.isClosing() {
+				return nil
+			}
+			return err
+		}
+		full, ok := cs.admit(conn)
+		if !ok {
+			conn.Close()
+			continue
+		}
+		go func() {
+			defer cs.release(conn)
+			handle(conn, full)
+		}()
+	}
+}
+
+// setCutOff replaces how conn is ended when the shutdown grace period is
+// over, so that the front end can say goodbye in its own protocol.
+func (cs *connServer) setCutOff(conn net.Conn, cutOff func()) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if _, ok := cs.conns[conn]; ok {
+		cs.conns[conn] = cutOff
+	}
+}
+
+// Shutdown stops accepting connections and waits for the running sessions
+// to finish. When ctx ends first, the remaining users are cut off.
+func (cs *connServer) Shutdown(ctx context.Context) error {
+	cs.mu.Lock()
+	cs.closing = true
+	if cs.ln != nil {
+		cs.ln.Close()
+	}
+	cs.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		cs.sessions.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	cs.mu.Lock()
+	for _, cutOff := range cs.conns {
+		cutOff()
+	}
+	cs.mu.Unlock()
+	<-done
+	return ctx.Err()
+}
+
+// activeSessions counts the connections being served.
+func (cs *connServer) activeSessions() int {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return len(cs.conns)
+}
+
+// telnetServer runs an independent session for every telnet connection,
+// the way a BBS door would.
+type telnetServer struct {
+	connServer
+	content *karabasan.Content
+
+	// IdleTimeout ends a session whose user has not typed for this long.
+	IdleTimeout time.Duration
+	// ScoresPath is the score table the sessions save to.
+	ScoresPath string
+	// Clock times the sessions' effects.
+	Clock karabasan.Clock
+	// NegotiationWait is how long to wait for the client's window size.
+	NegotiationWait time.Duration
+}
+
+func newTelnetServer(content *karabasan.Content) *telnetServer {
+	return &telnetServer{
+		content:         content,
+		Clock:           karabasan.RealClock{},
+		NegotiationWait: 500 * time.Millisecond,
+	}
+}
+
+// Serve accepts telnet connections on ln until Shutdown is called.
+func (srv *telnetServer) Serve(ln net.Listener) error {
+	return srv.serve(ln, srv.handle)
+}
+
+// handle runs one session on the connection.
+func (srv *telnetServer) handle(conn net.Conn, full bool) {
+	defer conn.Close()
+	c := newTelnetConn(conn, srv.IdleTimeout)
+	if full {
+		c.Write([]byte(srv.content.Serve.Busy + "\n"))
+		return
+	}
+	srv.setCutOff(conn, func() {
+		conn.SetWriteDeadline(time.Now().Add(time.Second))
+		c.Write([]byte("\n" + srv.content.Serve.Shutdown + "\n"))
+		conn.Close()
+	})
+
+	s := karabasan.NewTerminalSession(srv.content, c, c)
+	s.ScoresPath = srv.ScoresPath
+	s.OnError = func(err error) { log.Printf("%s: %v", conn.RemoteAddr(), err) }
+	s.Clock = srv.Clock
+	c.onResize = s.Resize
+	if err := c.negotiate(srv.NegotiationWait); err != nil {
+		log.Printf("%s: %v", conn.RemoteAddr(), err)
+		return
+	}
+	runRemoteSession(s, conn.RemoteAddr().String(), c)
+	if c.timedOut {
+		conn.SetWriteDeadline(time.Now().Add(time.Second))
+		fmt.Fprintln(c, "\n"+srv.content.Serve.Idle)
+	}
+}
+
+// runRemoteSession plays a session for a network user and logs it. A panic
+// ends only this session, with a crash report like the one the terminal
+// version writes.
+func runRemoteSession(s *karabasan.Session, addr string, out io.Writer) {
+	defer func() {
+		if r := recover(); r != nil {
+			path, err := writeCrashReport(s, r, debug.Stack())
+			if err != nil {
+				log.Printf("%s: crashed in %s: %v (writing the crash report failed: %v)", addr, s.Stage, r, err)
+				return
+			}
+			log.Printf("%s: crashed in %s: %v, see %s", addr, s.Stage, r, path)
+			fmt.Fprintf(out, "\n"+s.Content().Crash+"\n", filepath.Base(path))
+		}
+	}()
+	log.Printf("%s: session started, seed %d", addr, s.Seed)
+	if err := s.Run(karabasan.FirstStage); err != nil && err != karabasan.ErrInputClosed {
+		log.Printf("%s: %v", addr, err)
+	}
+	log.Printf("%s: session ended in %s", addr, s.Stage)
+}
+
+// sessionServer is a network front end that runSessionServer can drive.
+type sessionServer interface {
+	Serve(ln net.Listener) error
+	Shutdown(ctx context.Context) error
+	activeSessions() int
+}
+
+// runSessionServer serves on addr until SIGTERM or an interrupt, then gives
+// the running sessions grace to finish.
+func runSessionServer(srv sessionServer, addr string, grace time.Duration) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		fmt.Println("Error listening:", err)
+		os.Exit(1)
+	}
+	log.Printf("karabasan listening on %s", ln.Addr())
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ln) }()
+
+	select {
+	case err := <-served:
+		fmt.Println("Error serving:", err)
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+	stop()
+	log.Printf("shutting down, waiting up to %s for %d sessions", grace, srv.activeSessions())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); errors.Is(err, context.DeadlineExceeded) {
+		log.Printf("grace period over, closed the remaining sessions")
+	}
+	<-served
+}
+
+// runServe is the "serve" subcommand: a telnet server for multi-user play.
+func runServe(content *karabasan.Content, args []string) {
+	cmd := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := cmd.String("addr", ":2323", "address to listen on")
+	maxConns := cmd.Int("max-conns", 32, "number of sessions that can run at once; 0 for no limit")
+	idle := cmd.Duration("idle", 5*time.Minute, "end a session after the user has been silent this long; 0 to never")
+	grace := cmd.Duration("grace", 30*time.Second, "how long running sessions may go on after SIGTERM")
+	cmd.Parse(args)
+
+	srv := newTelnetServer(content)
+	srv.MaxConns = *maxConns
+	srv.IdleTimeout = *idle
+	if path, err := karabasan.DefaultScoresPath(); err == nil {
+		srv.ScoresPath = path
+	}
+	runSessionServer(srv, *addr, *grace)
+}
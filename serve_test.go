@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"k.go/karabasan"
+	"k.go/karabasan/clocktest"
+)
+
+// startTelnetServer serves on a local port with a fake clock, so sessions
+// type and think without delay.
+func startTelnetServer(t *testing.T, configure func(*telnetServer)) (*telnetServer, string) {
+	t.Helper()
+	content, err := karabasan.LoadContent("data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newTelnetServer(content)
+	srv.Clock = clocktest.New()
+	srv.NegotiationWait = 50 * time.Millisecond
+	if configure != nil {
+		configure(srv)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ln) }()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		srv.Shutdown(ctx)
+		if err := <-served; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	return srv, ln.Addr().String()
+}
+
+// telnetClient is a test client that collects everything the server sends.
+type telnetClient struct {
+	t    *testing.T
+	conn net.Conn
+	got  bytes.Buffer
+}
+
+func dialTelnet(t *testing.T, addr string) *telnetClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &telnetClient{t: t, conn: conn}
+}
+
+// waitFor reads until want has arrived and returns everything read so far.
+func (c *telnetClient) waitFor(want string) string {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 4096)
+	for !strings.Contains(c.got.String(), want) {
+		n, err := c.conn.Read(buf)
+		c.got.Write(buf[:n])
+		if err != nil {
+			c.t.Fatalf("waiting for %q: %v; got:\n%s", want, err, c.got.String())
+		}
+	}
+	return c.got.String()
+}
+
+// waitForClose reads until the server hangs up and returns everything read.
+func (c *telnetClient) waitForClose() string {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	rest, err := io.ReadAll(c.conn)
+	c.got.Write(rest)
+	if err != nil {
+		c.t.Fatalf("waiting for the server to hang up: %v; got:\n%s", err, c.got.String())
+	}
+	return c.got.String()
+}
+
+func (c *telnetClient) send(data string) {
+	c.t.Helper()
+	if _, err := c.conn.Write([]byte(data)); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func TestServeNegotiatesAndCentresForTheClientWidth(t *testing.T) {
+	_, addr := startTelnetServer(t, func(srv *telnetServer) { srv.NegotiationWait = 5 * time.Second })
+	c := dialTelnet(t, addr)
+	c.waitFor(string([]byte{telnetIAC, telnetDO, telnetNAWS}))
+	got := c.got.String()
+	for _, want := range [][]byte{{telnetIAC, telnetWILL, telnetEcho}, {telnetIAC, telnetWILL, telnetSGA}} {
+		if !strings.Contains(got, string(want)) {
+			t.Errorf("server did not offer option %d", want[2])
+		}
+	}
+
+	c.send(string([]byte{telnetIAC, telnetWILL, telnetNAWS, telnetIAC, telnetSB, telnetNAWS, 0, 100, 0, 30, telnetIAC, telnetSE}))
+	greeting := "Merhaba, hoş geldin."
+	got = c.waitFor(greeting)
+	padding := (100 - len(greeting)) / 2
+	if !strings.Contains(got, "\r\n"+strings.Repeat(" ", padding)+karabasan.ColorCyan+greeting) {
+		t.Errorf("greeting not centred for 100 columns:\n%q", got)
+	}
+}
+
+func TestServeEchoesAndEditsTheLine(t *testing.T) {
+	_, addr := startTelnetServer(t, nil)
+	c := dialTelnet(t, addr)
+	c.waitFor("senin adın ne güzelim?")
+	c.send("Alx\x7fi\r\x00")
+	got := c.waitFor("Ali. Hadi başlayalım.")
+	if !strings.Contains(got, "Alx\b \bi\r\n") {
+		t.Errorf("typing was not echoed with the correction:\n%q", got)
+	}
+}
+
+func TestServeTurnsAwayCallersWhenFull(t *testing.T) {
+	srv, addr := startTelnetServer(t, func(srv *telnetServer) { srv.MaxConns = 1 })
+	first := dialTelnet(t, addr)
+	first.waitFor("senin adın ne güzelim?")
+
+	second := dialTelnet(t, addr)
+	if got := second.waitForClose(); !strings.Contains(got, srv.content.Serve.Busy) {
+		t.Errorf("second caller got %q, want the busy message", got)
+	}
+}
+
+func TestServeEndsIdleSessions(t *testing.T) {
+	srv, addr := startTelnetServer(t, func(srv *telnetServer) { srv.IdleTimeout = 100 * time.Millisecond })
+	c := dialTelnet(t, addr)
+	if got := c.waitForClose(); !strings.Contains(got, srv.content.Serve.Idle) {
+		t.Errorf("idle caller got %q, want the idle message", got)
+	}
+}
+
+func TestServeShutdownCutsOffSessionsAfterGrace(t *testing.T) {
+	srv, addr := startTelnetServer(t, nil)
+	c := dialTelnet(t, addr)
+	c.waitFor("senin adın ne güzelim?")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got := c.waitForClose(); !strings.Contains(got, srv.content.Serve.Shutdown) {
+		t.Errorf("caller got %q, want the shutdown message", got)
+	}
+	if _, err := net.Dial("tcp", addr); err == nil {
+		t.Error("server still accepts connections after Shutdown")
+	}
+}
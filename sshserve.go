@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"k.go/karabasan"
+)
+
+// sshServer runs an independent session for every SSH connection. The
+// user's terminal is in raw mode under a PTY, so the server echoes and
+// edits the typed line itself, like the telnet front end.
+type sshServer struct {
+	connServer
+	content *karabasan.Content
+	config  *ssh.ServerConfig
+
+	// IdleTimeout ends a session whose user has not typed for this long.
+	IdleTimeout time.Duration
+	// ScoresPath is the score table the sessions save to.
+	ScoresPath string
+	// Clock times the sessions' effects.
+	Clock karabasan.Clock
+}
+
+// newSSHServer creates a server with the given host key. When allowed is
+// empty anyone may log in; otherwise only holders of those keys may.
+func newSSHServer(content *karabasan.Content, hostKey ssh.Signer, allowed []ssh.PublicKey) *sshServer {
+	config := &ssh.ServerConfig{}
+	if len(allowed) == 0 {
+		config.NoClientAuth = true
+	} else {
+		config.PublicKeyCallback = func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			for _, k := range allowed {
+				if bytes.Equal(k.Marshal(), key.Marshal()) {
+					return nil, nil
+				}
+			}
+			return nil, fmt.Errorf("key %s of %q is not allowed", ssh.FingerprintSHA256(key), meta.User())
+		}
+	}
+	config.AddHostKey(hostKey)
+	return &sshServer{content: content, config: config, Clock: karabasan.RealClock{}}
+}
+
+// loadHostKey reads the server's private key, generating an ed25519 key
+// at path on first run.
+func loadHostKey(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		block, err := ssh.MarshalPrivateKey(key, "karabasan host key")
+		if err != nil {
+			return nil, err
+		}
+		data = pem.EncodeToMemory(block)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return nil, err
+		}
+		log.Printf("generated host key %s", path)
+	} else if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(data)
+}
+
+// loadAuthorizedKeys reads public keys in OpenSSH authorized_keys format.
+func loadAuthorizedKeys(path string) ([]ssh.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys []ssh.PublicKey
+	for len(bytes.TrimSpace(data)) > 0 {
+		key, _, _, rest, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, key)
+		data = rest
+	}
+	return keys, nil
+}
+
+// Serve accepts SSH connections on ln until Shutdown is called.
+func (srv *sshServer) Serve(ln net.Listener) error {
+	return srv.serve(ln, srv.handle)
+}
+
+// handle runs the handshake and plays one session on the connection's
+// first session channel.
+func (srv *sshServer) handle(conn net.Conn, full bool) {
+	defer conn.Close()
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, srv.config)
+	if err != nil {
+		log.Printf("%s: %v", conn.RemoteAddr(), err)
+		return
+	}
+	defer sconn.Close()
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions are served here")
+			continue
+		}
+		ch, requests, err := newChannel.Accept()
+		if err != nil {
+			log.Printf("%s: %v", conn.RemoteAddr(), err)
+			return
+		}
+		srv.session(conn, sconn, ch, requests, full)
+		return
+	}
+}
+
+// ptyRequest is the payload of a "pty-req" channel request, RFC 4254 6.2.
+type ptyRequest struct {
+	Term          string
+	Cols, Rows    uint32
+	Width, Height uint32
+	Modes         string
+}
+
+// windowChange is the payload of a "window-change" request, RFC 4254 6.7.
+type windowChange struct {
+	Cols, Rows    uint32
+	Width, Height uint32
+}
+
+// session waits for the client to ask for a shell and plays the
+// conversation on the channel, resizing it as the client's window changes.
+func (srv *sshServer) session(conn net.Conn, sconn *ssh.ServerConn, ch ssh.Channel, requests <-chan *ssh.Request, full bool) {
+	defer ch.Close()
+	t := newSSHTerminal(ch, srv.IdleTimeout)
+	t.idleMessage = srv.content.Serve.Idle
+	s := karabasan.NewTerminalSession(srv.content, t, t)
+	s.ScoresPath = srv.ScoresPath
+	s.OnError = func(err error) { log.Printf("%s: %v", conn.RemoteAddr(), err) }
+	s.Clock = srv.Clock
+	s.LoginName = sconn.User()
+
+	shell := make(chan struct{})
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		started := false
+		for req := range requests {
+			ok := false
+			switch req.Type {
+			case "pty-req":
+				var pty ptyRequest
+				if ssh.Unmarshal(req.Payload, &pty) == nil && pty.Cols > 0 {
+					s.Resize(int(pty.Cols), int(pty.Rows))
+					ok = true
+				}
+			case "window-change":
+				var size windowChange
+				if ssh.Unmarshal(req.Payload, &size) == nil && size.Cols > 0 {
+					s.Resize(int(size.Cols), int(size.Rows))
+				}
+			case "shell":
+				ok = !started
+				if !started {
+					started = true
+					close(shell)
+				}
+			}
+			if req.WantReply {
+				req.Reply(ok, nil)
+			}
+		}
+	}()
+	select {
+	case <-shell:
+	case <-gone:
+		return
+	}
+
+	if full {
+		fmt.Fprintln(t, srv.content.Serve.Busy)
+		sendExitStatus(ch, 1)
+		return
+	}
+	srv.setCutOff(conn, func() {
+		fmt.Fprintln(t, "\n"+srv.content.Serve.Shutdown)
+		sconn.Close()
+	})
+	runRemoteSession(s, conn.RemoteAddr().String(), t)
+	sendExitStatus(ch, 0)
+}
+
+// sendExitStatus tells the client the shell has finished.
+func sendExitStatus(ch ssh.Channel, status uint32) {
+	ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+}
+
+// sshTerminal is the session's view of an SSH channel with a PTY.
+type sshTerminal struct {
+	ch ssh.Channel
+
+	// idle is how long a read may wait for the user; zero means forever.
+	// idleMessage is said before the channel is closed on the user.
+	idle        time.Duration
+	idleMessage string
+
+	edit    lineEditor
+	buf     [256]byte
+	pending []byte
+	wmu     sync.Mutex
+}
+
+func newSSHTerminal(ch ssh.Channel, idle time.Duration) *sshTerminal {
+	t := &sshTerminal{ch: ch, idle: idle}
+	t.edit.echo = func(p []byte) { t.writeRaw(p) }
+	return t
+}
+
+// Read returns the lines the user has finished typing. Ctrl-C and Ctrl-D
+// end the session, as they would at a shell.
+func (t *sshTerminal) Read(p []byte) (int, error) {
+	if t.idle > 0 {
+		timer := time.AfterFunc(t.idle, func() {
+			fmt.Fprintln(t, "\n"+t.idleMessage)
+			t.ch.Close()
+		})
+		defer timer.Stop()
+	}
+	for len(t.edit.ready) == 0 {
+		if len(t.pending) == 0 {
+			n, err := t.ch.Read(t.buf[:])
+			if n == 0 && err != nil {
+				return 0, err
+			}
+			t.pending = t.buf[:n]
+		}
+		for len(t.pending) > 0 && len(t.edit.ready) == 0 {
+			b := t.pending[0]
+			t.pending = t.pending[1:]
+			if b == 0x03 || b == 0x04 {
+				t.writeRaw([]byte("\r\n"))
+				return 0, io.EOF
+			}
+			t.edit.key(b)
+		}
+	}
+	return t.edit.read(p), nil
+}
+
+// Write sends session output, turning newlines into the CRLF a raw-mode
+// terminal needs.
+func (t *sshTerminal) Write(p []byte) (int, error) {
+	out := bytes.ReplaceAll(p, []byte("\r\n"), []byte("\n"))
+	out = bytes.ReplaceAll(out, []byte("\n"), []byte("\r\n"))
+	if _, err := t.writeRaw(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (t *sshTerminal) writeRaw(p []byte) (int, error) {
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+	return t.ch.Write(p)
+}
+
+// runServeSSH is the "serve-ssh" subcommand: an SSH server for multi-user play.
+func runServeSSH(content *karabasan.Content, args []string) {
+	defaultHostKey := "ssh_host_ed25519_key"
+	if dir, err := stateDir(); err == nil {
+		defaultHostKey = filepath.Join(dir, defaultHostKey)
+	}
+	cmd := flag.NewFlagSet("serve-ssh", flag.ExitOnError)
+	addr := cmd.String("addr", ":2222", "address to listen on")
+	hostKey := cmd.String("host-key", defaultHostKey, "the server's private key; generated when missing")
+	authorizedKeys := cmd.String("authorized-keys", "", "only let in the holders of the keys in this authorized_keys file")
+	maxConns := cmd.Int("max-conns", 32, "number of sessions that can run at once; 0 for no limit")
+	idle := cmd.Duration("idle", 5*time.Minute, "end a session after the user has been silent this long; 0 to never")
+	grace := cmd.Duration("grace", 30*time.Second, "how long running sessions may go on after SIGTERM")
+	cmd.Parse(args)
+
+	signer, err := loadHostKey(*hostKey)
+	if err != nil {
+		fmt.Println("Error loading host key:", err)
+		os.Exit(1)
+	}
+	var allowed []ssh.PublicKey
+	if *authorizedKeys != "" {
+		allowed, err = loadAuthorizedKeys(*authorizedKeys)
+		if err != nil {
+			fmt.Println("Error loading authorized keys:", err)
+			os.Exit(1)
+		}
+	}
+
+	srv := newSSHServer(content, signer, allowed)
+	srv.MaxConns = *maxConns
+	srv.IdleTimeout = *idle
+	if path, err := karabasan.DefaultScoresPath(); err == nil {
+		srv.ScoresPath = path
+	}
+	runSessionServer(srv, *addr, *grace)
+}
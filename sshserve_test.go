@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"k.go/karabasan"
+	"k.go/karabasan/clocktest"
+)
+
+// newTestKey makes a throwaway ed25519 key.
+func newTestKey(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+// startSSHServer serves on a local port with a fake clock and returns the
+// address and the host key to expect.
+func startSSHServer(t *testing.T, allowed []ssh.PublicKey) (*sshServer, string, ssh.PublicKey) {
+	t.Helper()
+	content, err := karabasan.LoadContent("data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := loadHostKey(filepath.Join(t.TempDir(), "host_key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newSSHServer(content, hostKey, allowed)
+	srv.Clock = clocktest.New()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ln) }()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		srv.Shutdown(ctx)
+		if err := <-served; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	return srv, ln.Addr().String(), hostKey.PublicKey()
+}
+
+// lockedBuffer collects a session's output while the test reads it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// sshShell is a client session with a PTY and a running shell.
+type sshShell struct {
+	t       *testing.T
+	session *ssh.Session
+	stdin   io.WriteCloser
+	out     *lockedBuffer
+}
+
+func dialSSHShell(t *testing.T, addr, user string, hostKey ssh.PublicKey, key ssh.Signer, cols, rows int) *sshShell {
+	t.Helper()
+	client, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(key)},
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+		Timeout:         5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sh := &sshShell{t: t, session: session, out: &lockedBuffer{}}
+	session.Stdout = sh.out
+	if sh.stdin, err = session.StdinPipe(); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.RequestPty("xterm", rows, cols, ssh.TerminalModes{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.Shell(); err != nil {
+		t.Fatal(err)
+	}
+	return sh
+}
+
+// waitFor polls the output until want has arrived and returns all of it.
+func (sh *sshShell) waitFor(want string) string {
+	sh.t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(sh.out.String(), want) {
+		if time.Now().After(deadline) {
+			sh.t.Fatalf("waiting for %q; got:\n%q", want, sh.out.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return sh.out.String()
+}
+
+func (sh *sshShell) send(keys string) {
+	sh.t.Helper()
+	if _, err := sh.stdin.Write([]byte(keys)); err != nil {
+		sh.t.Fatal(err)
+	}
+}
+
+func TestServeSSHUsesTheLoginNameAndWindowSize(t *testing.T) {
+	_, addr, hostKey := startSSHServer(t, nil)
+	sh := dialSSHShell(t, addr, "Mehmet", hostKey, newTestKey(t), 100, 30)
+
+	greeting := "Merhaba, hoş geldin."
+	got := sh.waitFor("Mehmet diye girdin")
+	if padding := (100 - len(greeting)) / 2; !strings.Contains(got, "\r\n"+strings.Repeat(" ", padding)+karabasan.ColorCyan+greeting) {
+		t.Errorf("greeting not centred for the 100 column PTY:\n%q", got)
+	}
+
+	if err := sh.session.WindowChange(30, 60); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	sh.send("e\r")
+	got = sh.waitFor("kaç yaşındasın?")
+	if !strings.Contains(got, "e\r\n") {
+		t.Errorf("the answer was not echoed:\n%q", got)
+	}
+	if !strings.Contains(got, "Mehmet. Hadi başlayalım.") {
+		t.Errorf("the login name was not taken as the user's name:\n%q", got)
+	}
+	if !strings.Contains(got, "\r\n"+karabasan.ColorGreen+strings.Repeat("-", 60)+karabasan.ColorReset+"\r\n") {
+		t.Errorf("the separator did not follow the window change to 60 columns:\n%q", got)
+	}
+
+	sh.send("\x04")
+	if err := sh.session.Wait(); err != nil {
+		t.Errorf("shell ended with %v, want exit status 0", err)
+	}
+}
+
+func TestServeSSHTeasesGenericLogins(t *testing.T) {
+	_, addr, hostKey := startSSHServer(t, nil)
+	sh := dialSSHShell(t, addr, "karabasan", hostKey, newTestKey(t), 80, 24)
+	sh.waitFor("karabasan?! öyle isim mi olur lan!")
+	sh.waitFor("senin adın ne güzelim?")
+}
+
+func TestServeSSHAllowList(t *testing.T) {
+	allowed, stranger := newTestKey(t), newTestKey(t)
+	_, addr, hostKey := startSSHServer(t, []ssh.PublicKey{allowed.PublicKey()})
+
+	config := &ssh.ClientConfig{
+		User:            "Ali",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(stranger)},
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+		Timeout:         5 * time.Second,
+	}
+	if client, err := ssh.Dial("tcp", addr, config); err == nil {
+		client.Close()
+		t.Fatal("a key outside the allow-list got in")
+	}
+	dialSSHShell(t, addr, "Ali", hostKey, allowed, 80, 24).waitFor("Ali diye girdin")
+}
+
+func TestLoadHostKeyGeneratesOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "host_key")
+	first, err := loadHostKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := loadHostKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.PublicKey().Marshal(), second.PublicKey().Marshal()) {
+		t.Error("the host key changed between runs")
+	}
+}
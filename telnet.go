@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// Telnet commands and options, RFC 854, 857, 858 and 1073.
+const (
+	telnetSE   = 240
+	telnetSB   = 250
+	telnetWILL = 251
+	telnetWONT = 252
+	telnetDO   = 253
+	telnetDONT = 254
+	telnetIAC  = 255
+
+	telnetEcho = 1
+	telnetSGA  = 3
+	telnetNAWS = 31
+)
+
+// telnetState is where the reader is in the telnet byte stream.
+type telnetState int
+
+const (
+	telnetData telnetState = iota
+	telnetCommand
+	telnetOption
+	telnetSub
+	telnetSubIAC
+)
+
+// telnetConn speaks just enough telnet for a session: it asks the client
+// for character mode with the server echoing (ECHO, SGA) and for window
+// size reports (NAWS), and it edits the typed line itself, handing the
+// session whole lines. Clients that ignore the negotiation, such as nc,
+// still work in their own line mode.
+type telnetConn struct {
+	conn net.Conn
+	raw  *bufio.Reader
+
+	// idle is how long a read may wait for the user; zero means forever.
+	idle     time.Duration
+	timedOut bool
+
+	// onResize receives the window sizes the client reports.
+	onResize func(cols, rows int)
+	sized    bool
+
+	state telnetState
+	cmd   byte
+	sub   []byte
+	edit  lineEditor
+
+	wmu sync.Mutex
+}
+
+func newTelnetConn(conn net.Conn, idle time.Duration) *telnetConn {
+	c := &telnetConn{conn: conn, raw: bufio.NewReader(conn), idle: idle}
+	c.edit.echo = func(p []byte) { c.writeRaw(p) }
+	return c
+}
+
+// negotiate asks for the options and waits up to wait for the client's
+// first window size, so that the greeting is already centred for it.
+func (c *telnetConn) negotiate(wait time.Duration) error {
+	_, err := c.writeRaw([]byte{
+		telnetIAC, telnetWILL, telnetEcho,
+		telnetIAC, telnetWILL, telnetSGA,
+		telnetIAC, telnetDO, telnetNAWS,
+	})
+	if err != nil {
+		return err
+	}
+	c.conn.SetReadDeadline(time.Now().Add(wait))
+	defer c.conn.SetReadDeadline(time.Time{})
+	for !c.sized {
+		b, err := c.raw.ReadByte()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return nil
+			}
+			return err
+		}
+		c.handle(b)
+	}
+	return nil
+}
+
+// Read returns the lines the user has finished typing.
+func (c *telnetConn) Read(p []byte) (int, error) {
+	for len(c.edit.ready) == 0 {
+		if c.idle > 0 && c.raw.Buffered() == 0 {
+			c.conn.SetReadDeadline(time.Now().Add(c.idle))
+		}
+		b, err := c.raw.ReadByte()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				c.timedOut = true
+			}
+			return 0, err
+		}
+		c.handle(b)
+	}
+	return c.edit.read(p), nil
+}
+
+// handle feeds one byte from the client through the telnet state machine.
+func (c *telnetConn) handle(b byte) {
+	switch c.state {
+	case telnetData:
+		if b == telnetIAC {
+			c.state = telnetCommand
+			return
+		}
+		c.edit.key(b)
+	case telnetCommand:
+		switch b {
+		case telnetIAC:
+			c.state = telnetData
+			c.edit.key(b)
+		case telnetWILL, telnetWONT, telnetDO, telnetDONT:
+			c.cmd = b
+			c.state = telnetOption
+		case telnetSB:
+			c.sub = c.sub[:0]
+			c.state = telnetSub
+		default:
+			c.state = telnetData
+		}
+	case telnetOption:
+		c.answer(c.cmd, b)
+		c.state = telnetData
+	case telnetSub:
+		if b == telnetIAC {
+			c.state = telnetSubIAC
+		} else {
+			c.sub = append(c.sub, b)
+		}
+	case telnetSubIAC:
+		switch b {
+		case telnetSE:
+			c.subnegotiation(c.sub)
+			c.state = telnetData
+		case telnetIAC:
+			c.sub = append(c.sub, b)
+			c.state = telnetSub
+		default:
+			c.state = telnetData
+		}
+	}
+}
+
+// answer replies to an option request from the client. The options the
+// server asked for are accepted silently; everything else is refused.
+func (c *telnetConn) answer(cmd, option byte) {
+	switch cmd {
+	case telnetWILL:
+		if option != telnetNAWS {
+			c.writeRaw([]byte{telnetIAC, telnetDONT, option})
+		}
+	case telnetDO:
+		if option != telnetEcho && option != telnetSGA {
+			c.writeRaw([]byte{telnetIAC, telnetWONT, option})
+		}
+	}
+}
+
+// subnegotiation handles a window size report: NAWS width and height, 16 bits each.
+func (c *telnetConn) subnegotiation(sub []byte) {
+	if len(sub) < 5 || sub[0] != telnetNAWS {
+		return
+	}
+	cols := int(sub[1])<<8 | int(sub[2])
+	rows := int(sub[3])<<8 | int(sub[4])
+	if cols == 0 {
+		return
+	}
+	c.sized = true
+	if c.onResize != nil {
+		c.onResize(cols, rows)
+	}
+}
+
+// Write sends session output, turning newlines into CRLF and escaping IAC.
+func (c *telnetConn) Write(p []byte) (int, error) {
+	out := bytes.ReplaceAll(p, []byte("\r\n"), []byte("\n"))
+	out = bytes.ReplaceAll(out, []byte("\n"), []byte("\r\n"))
+	out = bytes.ReplaceAll(out, []byte{telnetIAC}, []byte{telnetIAC, telnetIAC})
+	if _, err := c.writeRaw(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *telnetConn) writeRaw(p []byte) (int, error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return c.conn.Write(p)
+}
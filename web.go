@@ -0,0 +1,240 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"flag"
+	"io"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/websocket"
+
+	"k.go/karabasan"
+)
+
+// webFiles is the chat page served by the web front end.
+//
+//go:embed web
+var webFiles embed.FS
+
+// webMessage is one JSON message on the chat socket.
+//
+// The server sends "text" (a piece of the conversation, with the CSS class
+// of its colour), "busy", "shutdown" and "end". The browser sends "input"
+// (a line typed by the user) and "resize" (its width in characters).
+type webMessage struct {
+	Type  string `json:"type"`
+	Text  string `json:"text,omitempty"`
+	Class string `json:"class,omitempty"`
+	Cols  int    `json:"cols,omitempty"`
+	Rows  int    `json:"rows,omitempty"`
+}
+
+// ansiClasses maps the colours of the terminal theme to the CSS classes
+// of the chat page. The reset code ends any class.
+var ansiClasses = map[string]string{
+	karabasan.ColorCyan:    "ansi-cyan",
+	karabasan.ColorGreen:   "ansi-green",
+	karabasan.ColorMagenta: "ansi-magenta",
+	karabasan.ColorReset:   "",
+}
+
+// webServer serves the chat page and runs an independent session for
+// every WebSocket.
+type webServer struct {
+	connServer
+	content *karabasan.Content
+
+	// ScoresPath is the score table the sessions save to.
+	ScoresPath string
+	// Clock times the sessions' effects.
+	Clock karabasan.Clock
+}
+
+func newWebServer(content *karabasan.Content) *webServer {
+	return &webServer{content: content, Clock: karabasan.RealClock{}}
+}
+
+// Serve answers HTTP requests on ln until Shutdown is called.
+func (srv *webServer) Serve(ln net.Listener) error {
+	srv.listen(ln)
+	err := http.Serve(ln, srv.handler())
+	if srv.isClosing() {
+		return nil
+	}
+	return err
+}
+
+// handler routes the page, the health check and the chat socket.
+func (srv *webServer) handler() http.Handler {
+	static, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServerFS(static))
+	mux.HandleFunc("GET /api/health", srv.health)
+	mux.Handle("GET /ws", websocket.Server{Handler: srv.chat, Handshake: sameOrigin})
+	return mux
+}
+
+// sameOrigin refuses sockets opened by pages from other sites.
+func sameOrigin(config *websocket.Config, req *http.Request) error {
+	origin, err := url.Parse(req.Header.Get("Origin"))
+	if err != nil || origin.Host != req.Host {
+		return websocket.ErrBadWebSocketOrigin
+	}
+	config.Origin = origin
+	return nil
+}
+
+// health reports that the server is up and how full it is.
+func (srv *webServer) health(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":      "ok",
+		"sessions":    srv.activeSessions(),
+		"maxSessions": srv.MaxConns,
+	})
+}
+
+// chat plays one session on a socket.
+func (srv *webServer) chat(ws *websocket.Conn) {
+	defer ws.Close()
+	full, ok := srv.admit(ws)
+	if !ok {
+		return
+	}
+	defer srv.release(ws)
+	out := &webOutput{sink: func(msg webMessage) error {
+		ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return websocket.JSON.Send(ws, msg)
+	}}
+	if full {
+		out.send(webMessage{Type: "busy", Text: srv.content.Serve.Busy})
+		return
+	}
+	srv.setCutOff(ws, func() {
+		out.send(webMessage{Type: "shutdown", Text: srv.content.Serve.Shutdown})
+		ws.Close()
+	})
+
+	in, typed := io.Pipe()
+	s := karabasan.NewTerminalSession(srv.content, in, out)
+	s.ScoresPath = srv.ScoresPath
+	s.OnError = func(err error) { log.Printf("%s: %v", ws.Request().RemoteAddr, err) }
+	s.Clock = srv.Clock
+	go func() {
+		defer typed.Close()
+		for {
+			var msg webMessage
+			if err := websocket.JSON.Receive(ws, &msg); err != nil {
+				return
+			}
+			switch msg.Type {
+			case "input":
+				line := strings.NewReplacer("\r", " ", "\n", " ").Replace(msg.Text)
+				if _, err := io.WriteString(typed, line+"\n"); err != nil {
+					return
+				}
+			case "resize":
+				if msg.Cols > 0 {
+					s.Resize(msg.Cols, msg.Rows)
+				}
+			}
+		}
+	}()
+
+	runRemoteSession(s, ws.Request().RemoteAddr, out)
+	out.send(webMessage{Type: "end"})
+}
+
+// webOutput turns the session's terminal output into text messages. The
+// typewriter writes one character at a time, so the page gets the
+// conversation as it is typed. Colour codes become CSS classes, even when
+// they arrive a byte at a time.
+type webOutput struct {
+	// sink delivers one message to the page.
+	sink func(webMessage) error
+
+	mu      sync.Mutex
+	class   string
+	escape  []byte // an unfinished escape sequence
+	partial []byte // an unfinished UTF-8 character
+}
+
+func (o *webOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var text []byte
+	flush := func() error {
+		if len(text) == 0 {
+			return nil
+		}
+		err := o.sendLocked(webMessage{Type: "text", Text: string(text), Class: o.class})
+		text = text[:0]
+		return err
+	}
+	for _, b := range p {
+		switch {
+		case len(o.escape) > 0:
+			o.escape = append(o.escape, b)
+			if (len(o.escape) == 2 && b != '[') || (len(o.escape) > 2 && b >= '@' && b <= '~') {
+				if err := flush(); err != nil {
+					return 0, err
+				}
+				if class, ok := ansiClasses[string(o.escape)]; ok {
+					o.class = class
+				}
+				o.escape = o.escape[:0]
+			}
+		case b == 0x1b:
+			o.escape = append(o.escape, b)
+		default:
+			o.partial = append(o.partial, b)
+			if utf8.FullRune(o.partial) {
+				text = append(text, o.partial...)
+				o.partial = o.partial[:0]
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// send writes a message that is not conversation text.
+func (o *webOutput) send(msg webMessage) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.sendLocked(msg)
+}
+
+func (o *webOutput) sendLocked(msg webMessage) error {
+	return o.sink(msg)
+}
+
+// runWeb is the "web" subcommand: the chat page and its sockets.
+func runWeb(content *karabasan.Content, args []string) {
+	cmd := flag.NewFlagSet("web", flag.ExitOnError)
+	addr := cmd.String("addr", ":8080", "address to listen on")
+	maxSessions := cmd.Int("max-sessions", 32, "number of chats that can run at once; 0 for no limit")
+	grace := cmd.Duration("grace", 30*time.Second, "how long running chats may go on after SIGTERM")
+	cmd.Parse(args)
+
+	srv := newWebServer(content)
+	srv.MaxConns = *maxSessions
+	if path, err := karabasan.DefaultScoresPath(); err == nil {
+		srv.ScoresPath = path
+	}
+	runSessionServer(srv, *addr, *grace)
+}
@@ -0,0 +1,89 @@
+// The chat page: it shows the conversation as the server types it and
+// sends the user's answers back, one line per message.
+"use strict";
+
+const transcript = document.getElementById("transcript");
+const form = document.getElementById("chat");
+const answer = document.getElementById("answer");
+const status = document.getElementById("status");
+
+const scheme = location.protocol === "https:" ? "wss:" : "ws:";
+const socket = new WebSocket(scheme + "//" + location.host + "/ws");
+
+// append adds text in the given CSS class, following the terminal's
+// backspaces, which the thinking cursor uses.
+function append(text, className) {
+  for (const part of text.split(/(\x08)/)) {
+    if (part === "\b") {
+      const last = transcript.lastChild;
+      if (last) {
+        last.textContent = last.textContent.slice(0, -1);
+        if (last.textContent === "") last.remove();
+      }
+    } else if (part !== "") {
+      const last = transcript.lastChild;
+      if (last && last.className === className) {
+        last.textContent += part;
+      } else {
+        const span = document.createElement("span");
+        span.className = className;
+        span.textContent = part;
+        transcript.appendChild(span);
+      }
+    }
+  }
+  transcript.scrollTop = transcript.scrollHeight;
+}
+
+// columns is how many characters fit on a line of the transcript.
+function columns() {
+  const probe = document.createElement("span");
+  probe.textContent = "0".repeat(10);
+  transcript.appendChild(probe);
+  const width = probe.getBoundingClientRect().width / 10;
+  probe.remove();
+  const style = getComputedStyle(transcript);
+  const inner = transcript.clientWidth - parseFloat(style.paddingLeft) - parseFloat(style.paddingRight);
+  return Math.max(20, Math.floor(inner / width));
+}
+
+function sendSize() {
+  socket.send(JSON.stringify({ type: "resize", cols: columns(), rows: 24 }));
+}
+
+function finish() {
+  answer.disabled = true;
+  form.querySelector("button").disabled = true;
+  status.hidden = false;
+}
+
+socket.addEventListener("open", () => {
+  sendSize();
+  window.addEventListener("resize", sendSize);
+});
+
+socket.addEventListener("message", (event) => {
+  const msg = JSON.parse(event.data);
+  switch (msg.type) {
+    case "text":
+      append(msg.text, msg.class || "");
+      break;
+    case "busy":
+    case "shutdown":
+      append("\n" + msg.text + "\n", "notice");
+      break;
+    case "end":
+      finish();
+      break;
+  }
+});
+
+socket.addEventListener("close", finish);
+
+form.addEventListener("submit", (event) => {
+  event.preventDefault();
+  if (socket.readyState !== WebSocket.OPEN) return;
+  socket.send(JSON.stringify({ type: "input", text: answer.value }));
+  append(answer.value + "\n", "user");
+  answer.value = "";
+});
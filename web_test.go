@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"k.go/karabasan"
+	"k.go/karabasan/clocktest"
+)
+
+func startWebServer(t *testing.T, configure func(*webServer)) (*webServer, *httptest.Server) {
+	t.Helper()
+	content, err := karabasan.LoadContent("data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newWebServer(content)
+	srv.Clock = clocktest.New()
+	if configure != nil {
+		configure(srv)
+	}
+	ts := httptest.NewServer(srv.handler())
+	t.Cleanup(ts.Close)
+	return srv, ts
+}
+
+// webChat is a test browser on the chat socket.
+type webChat struct {
+	t    *testing.T
+	ws   *websocket.Conn
+	msgs []webMessage
+	text strings.Builder
+}
+
+func dialWebChat(t *testing.T, ts *httptest.Server) *webChat {
+	t.Helper()
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", "", ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return &webChat{t: t, ws: ws}
+}
+
+// waitFor receives messages until the conversation text contains want.
+func (c *webChat) waitFor(want string) {
+	c.t.Helper()
+	c.ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for !strings.Contains(c.text.String(), want) {
+		var msg webMessage
+		if err := websocket.JSON.Receive(c.ws, &msg); err != nil {
+			c.t.Fatalf("waiting for %q: %v; got:\n%s", want, err, c.text.String())
+		}
+		c.msgs = append(c.msgs, msg)
+		c.text.WriteString(msg.Text)
+	}
+}
+
+func (c *webChat) send(msg webMessage) {
+	c.t.Helper()
+	if err := websocket.JSON.Send(c.ws, msg); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func TestWebChatStreamsColouredText(t *testing.T) {
+	_, ts := startWebServer(t, nil)
+	c := dialWebChat(t, ts)
+	c.send(webMessage{Type: "resize", Cols: 60, Rows: 24})
+	c.waitFor("senin adın ne güzelim?")
+	c.send(webMessage{Type: "input", Text: "Ali"})
+	c.waitFor("Ali. Hadi başlayalım.")
+
+	var greeting []string
+	for _, msg := range c.msgs {
+		if msg.Type == "text" && msg.Class == "ansi-cyan" {
+			greeting = append(greeting, msg.Text)
+		}
+	}
+	if want := "Merhaba, hoş geldin."; len(greeting) < len([]rune(want)) || !strings.HasPrefix(strings.Join(greeting, ""), want) {
+		t.Errorf("the greeting came as %q, want it one character at a time", greeting)
+	}
+	if !strings.Contains(c.text.String(), strings.Repeat("-", 60)) {
+		t.Errorf("the separator did not follow the 60 column resize:\n%s", c.text.String())
+	}
+	if strings.Contains(c.text.String(), "\033") {
+		t.Errorf("escape codes leaked into the text:\n%q", c.text.String())
+	}
+}
+
+func TestWebChatSessionCap(t *testing.T) {
+	srv, ts := startWebServer(t, func(srv *webServer) { srv.MaxConns = 1 })
+	dialWebChat(t, ts).waitFor("senin adın ne güzelim?")
+
+	second := dialWebChat(t, ts)
+	var msg webMessage
+	second.ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := websocket.JSON.Receive(second.ws, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != "busy" || msg.Text != srv.content.Serve.Busy {
+		t.Errorf("second chat got %+v, want the busy message", msg)
+	}
+}
+
+func TestWebRefusesOtherOrigins(t *testing.T) {
+	_, ts := startWebServer(t, nil)
+	if ws, err := websocket.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", "", "http://evil.example"); err == nil {
+		ws.Close()
+		t.Error("a page from another site opened a chat")
+	}
+}
+
+func TestWebHealthAndPage(t *testing.T) {
+	_, ts := startWebServer(t, func(srv *webServer) { srv.MaxConns = 7 })
+
+	resp, err := http.Get(ts.URL + "/api/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var health map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]any{"status": "ok", "sessions": 0.0, "maxSessions": 7.0}; !reflect.DeepEqual(health, want) {
+		t.Errorf("health = %v, want %v", health, want)
+	}
+
+	resp, err = http.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	page, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(page), "<title>Karabasan</title>") {
+		t.Errorf("/ did not serve the chat page:\n%s", page)
+	}
+}
+
+func TestWebOutputMapsColoursToClasses(t *testing.T) {
+	var got []webMessage
+	out := &webOutput{sink: func(msg webMessage) error {
+		got = append(got, msg)
+		return nil
+	}}
+	// Escape codes and multi-byte characters split across writes, the way
+	// the typewriter produces them.
+	for _, chunk := range []string{"\033[3", "6mş", "\xc5", "\x9f", "\033[0m", "> "} {
+		out.Write([]byte(chunk))
+	}
+	want := []webMessage{
+		{Type: "text", Text: "ş", Class: "ansi-cyan"},
+		{Type: "text", Text: "ş", Class: "ansi-cyan"},
+		{Type: "text", Text: "> "},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("messages = %+v, want %+v", got, want)
+	}
+}